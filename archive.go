@@ -0,0 +1,212 @@
+package gosnap
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ecwid/gosnap/registry"
+)
+
+const manifestFile = "manifest.json"
+
+var (
+	// ErrNotListable is returned by Export if neither the registry nor the ones it wraps implement registry.Lister
+	ErrNotListable = errors.New("registry can't list keys")
+	// ErrArchive is returned by Import for a malformed archive
+	ErrArchive = errors.New("malformed snapshot archive")
+)
+
+type ManifestEntry struct {
+	Key      string            `json:"key"`
+	File     string            `json:"file"`
+	Hash     string            `json:"hash"`
+	Width    int               `json:"width"`
+	Height   int               `json:"height"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+type Manifest struct {
+	Created   int64           `json:"created"`
+	Prefix    string          `json:"prefix"`
+	Baselines []ManifestEntry `json:"baselines"`
+}
+
+func archiveFile(key string) string {
+	return "baselines/" + key + ".png"
+}
+
+// Export writes baselines under the prefix as a tar of png files led by manifest.json
+func Export(prefix string, w io.Writer) error {
+	lister, ok := registry.AsLister(defaultRegistry)
+	if !ok {
+		return ErrNotListable
+	}
+	keys, err := lister.List(prefix)
+	if err != nil {
+		return err
+	}
+
+	manifest := Manifest{Created: time.Now().Unix(), Prefix: prefix}
+	for _, key := range keys {
+		if registry.IsImmutableKey(key) || isHistoryKey(key) {
+			continue
+		}
+		var baseline = new(Snapshot)
+		if err = baseline.Head(key); err != nil {
+			return err
+		}
+		// approvals, batches and audit logs have no hash
+		if _, ok := baseline.Metadata[dataHash]; !ok {
+			continue
+		}
+		x, y := baseline.GetSize()
+		metadata := map[string]string{}
+		for k, v := range baseline.Metadata {
+			if k != dataHash && k != keyX && k != keyY && k != "last-modified-unix" {
+				metadata[k] = v
+			}
+		}
+		manifest.Baselines = append(manifest.Baselines, ManifestEntry{
+			Key:      key,
+			File:     archiveFile(key),
+			Hash:     baseline.Metadata[dataHash],
+			Width:    x,
+			Height:   y,
+			Metadata: metadata,
+		})
+	}
+
+	tw := tar.NewWriter(w)
+	body, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = writeTarFile(tw, manifestFile, body); err != nil {
+		return err
+	}
+	for _, entry := range manifest.Baselines {
+		obj, err := defaultRegistry.Pull(entry.Key)
+		if err != nil {
+			return errors.Join(ErrPullSnapshot, err)
+		}
+		if err = writeTarFile(tw, entry.File, obj.Body); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeTarFile(tw *tar.Writer, name string, body []byte) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(body)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(body)
+	return err
+}
+
+// ImportOptions configures ImportWithOptions
+type ImportOptions struct {
+	// HashSize the baselines were hashed with, 1024 by default like NewMatcher
+	HashSize uint
+}
+
+// Import pushes baselines of an Export archive to the registry, it returns the number of imported baselines
+func Import(r io.Reader) (int, error) {
+	return ImportWithOptions(r, ImportOptions{})
+}
+
+// validKey refuses keys of anything but baselines under the manifest's prefix,
+// an archive must not overwrite approvals, batches, audit logs or histories
+func (m Manifest) validKey(entry ManifestEntry) error {
+	switch {
+	case entry.Key == "" || entry.File != archiveFile(entry.Key):
+		return errors.New("file doesn't match the key")
+	case !strings.HasPrefix(entry.Key, m.Prefix):
+		return errors.New("key is out of the manifest prefix")
+	case registry.IsImmutableKey(entry.Key), strings.HasSuffix(entry.Key, ".audit"), isHistoryKey(entry.Key):
+		return errors.New("key isn't a baseline one")
+	}
+	// an existing object without hash isn't a snapshot
+	if data, err := defaultRegistry.Head(entry.Key); err == nil {
+		if _, ok := data[dataHash]; !ok {
+			return errors.New("key is taken by an object that isn't a baseline")
+		}
+	}
+	return nil
+}
+
+// ImportWithOptions is Import verifying hashes of the baselines with the hash size from opts
+func ImportWithOptions(r io.Reader, opts ImportOptions) (int, error) {
+	if opts.HashSize == 0 {
+		opts.HashSize = 1024
+	}
+	tr := tar.NewReader(r)
+	header, err := tr.Next()
+	if err != nil || header.Name != manifestFile {
+		return 0, errors.Join(ErrArchive, errors.New("manifest.json must be the first file"), err)
+	}
+	var manifest Manifest
+	if err = json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return 0, errors.Join(ErrArchive, err)
+	}
+	entries := map[string]ManifestEntry{}
+	for _, entry := range manifest.Baselines {
+		entries[entry.File] = entry
+	}
+
+	imported := 0
+	for {
+		header, err = tr.Next()
+		if err == io.EOF {
+			return imported, nil
+		}
+		if err != nil {
+			return imported, errors.Join(ErrArchive, err)
+		}
+		entry, ok := entries[header.Name]
+		if !ok || !strings.HasSuffix(header.Name, ".png") {
+			continue
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			return imported, errors.Join(ErrArchive, err)
+		}
+		if err = manifest.validKey(entry); err != nil {
+			return imported, errors.Join(ErrArchive, fmt.Errorf("%s: %w", entry.Key, err))
+		}
+		img, err := png.Decode(bytes.NewReader(body))
+		if err != nil {
+			return imported, errors.Join(ErrArchive, fmt.Errorf("%s: %w", entry.Key, err))
+		}
+		if size := img.Bounds().Size(); size.X != entry.Width || size.Y != entry.Height {
+			return imported, errors.Join(ErrArchive, fmt.Errorf("%s: size differs from manifest", entry.Key))
+		}
+		if !MakeHash(img, opts.HashSize).Equal(hashString(entry.Hash), 0) {
+			return imported, errors.Join(ErrArchive, fmt.Errorf("%s: hash differs from manifest", entry.Key))
+		}
+		data := map[string]string{}
+		for k, v := range entry.Metadata {
+			data[k] = v
+		}
+		data[dataHash] = entry.Hash
+		data[keyX] = fmt.Sprint(entry.Width)
+		data[keyY] = fmt.Sprint(entry.Height)
+		if err = defaultRegistry.Push(entry.Key, registry.Object{Body: body, Data: data}); err != nil {
+			return imported, errors.Join(ErrPushSnapshot, err)
+		}
+		imported++
+	}
+}
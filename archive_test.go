@@ -0,0 +1,91 @@
+package gosnap
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ecwid/gosnap/registry"
+	"github.com/ecwid/gosnap/registry/retry"
+)
+
+func TestExportImport(t *testing.T) {
+	SetRegistry(newMemRegistry())
+	matcher := NewMatcher("run").ApprovalEnabled(false, "").SnapshotSource("2023").Metadata("os", "linux")
+	_ = matcher.New("page").Compare(testImage(64, 64, 16))
+	_ = matcher.New("page").Compare(testImage(64, 64, 48))
+	_ = matcher.New("other").Compare(testImage(32, 32, 8))
+
+	var buf bytes.Buffer
+	if err := Export("2023/", &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	SetRegistry(newMemRegistry())
+	n, err := Import(&buf)
+	if err != nil || n != 2 {
+		t.Fatal("expected two baselines, no targets and overlays", n, err)
+	}
+	baseline := new(Snapshot)
+	if err = baseline.Head("2023/page"); err != nil || baseline.Metadata["os"] != "linux" {
+		t.Error("expected imported metadata", baseline.Metadata, err)
+	}
+	if err = matcher.New("page").Compare(testImage(64, 64, 16)); err != nil {
+		t.Error("imported baseline must match", err)
+	}
+}
+
+func TestExportWrapped(t *testing.T) {
+	SetRegistry(registry.Wrap(newMemRegistry(), retry.Middleware(retry.Options{}), registry.Observe(func(registry.Op, string) func(int, time.Duration, error) {
+		return func(int, time.Duration, error) {}
+	})))
+	_ = NewMatcher("run").ApprovalEnabled(false, "").New("page").Compare(testImage(64, 64, 16))
+	if err := Export("", io.Discard); err != nil {
+		t.Error("expected export through wrappers", err)
+	}
+}
+
+func testArchive(t *testing.T, entry ManifestEntry, body []byte) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	manifest, _ := json.Marshal(Manifest{Baselines: []ManifestEntry{entry}})
+	if err := writeTarFile(tw, manifestFile, manifest); err != nil {
+		t.Fatal(err)
+	}
+	_ = writeTarFile(tw, entry.File, body)
+	_ = tw.Close()
+	return &buf
+}
+
+func TestImportValidates(t *testing.T) {
+	SetRegistry(newMemRegistry())
+	img := testImage(64, 64, 16)
+	body, _ := encodePng(img)
+	entry := ManifestEntry{Key: "page", File: archiveFile("page"), Hash: MakeHash(img, 1024).String(), Width: 64, Height: 64}
+	if n, err := Import(testArchive(t, entry, body)); err != nil || n != 1 {
+		t.Fatal("expected valid archive imported", n, err)
+	}
+
+	tampered := entry
+	tampered.Hash = MakeHash(testImage(64, 64, 48), 1024).String()
+	if _, err := Import(testArchive(t, tampered, body)); !errors.Is(err, ErrArchive) {
+		t.Error("expected hash mismatch", err)
+	}
+
+	_ = NewSyncedOps().Accept("approvals", hashString("1"), "qa")
+	for _, key := range []string{"approvals", "approvals.audit", "page.history"} {
+		hijack := entry
+		hijack.Key, hijack.File = key, archiveFile(key)
+		if _, err := Import(testArchive(t, hijack, body)); !errors.Is(err, ErrArchive) {
+			t.Error("expected key refused", key, err)
+		}
+	}
+	var approvals = new(Approvals)
+	if err := approvals.Pull("approvals"); err != nil || len(approvals.Value) != 1 {
+		t.Error("approvals must be intact", approvals.Value, err)
+	}
+}
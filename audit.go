@@ -0,0 +1,107 @@
+package gosnap
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ecwid/gosnap/registry"
+)
+
+type AuditAction string
+
+const (
+	AuditAccept  AuditAction = "accept"
+	AuditDecline AuditAction = "decline"
+	AuditExpire  AuditAction = "expire"
+)
+
+// AuditUnknownUser is recorded by the deprecated Synced.Decline that doesn't know who declined
+const AuditUnknownUser = "unknown"
+
+type AuditEntry struct {
+	Ts      int64       `json:"ts"`
+	Action  AuditAction `json:"action"`
+	User    string      `json:"user"`
+	Role    string      `json:"role,omitempty"`
+	Hash    Hash        `json:"hash"`
+	Key     string      `json:"key,omitempty"`
+	Comment string      `json:"comment,omitempty"`
+}
+
+func newAuditEntry(action AuditAction, approval Approval) AuditEntry {
+	return AuditEntry{
+		Ts:      getUnixTs(),
+		Action:  action,
+		User:    approval.Approver,
+		Role:    approval.Role,
+		Hash:    approval.Hash,
+		Key:     approval.Key,
+		Comment: approval.Comment,
+	}
+}
+
+// AuditFilter selects audit entries, empty fields match everything
+type AuditFilter struct {
+	Key  string
+	User string
+	From time.Time
+	To   time.Time
+}
+
+func (f AuditFilter) match(entry AuditEntry) bool {
+	ts := time.Unix(entry.Ts, 0)
+	switch {
+	case f.Key != "" && f.Key != entry.Key:
+		return false
+	case f.User != "" && f.User != entry.User:
+		return false
+	case !f.From.IsZero() && ts.Before(f.From):
+		return false
+	case !f.To.IsZero() && ts.After(f.To):
+		return false
+	}
+	return true
+}
+
+// AuditLog is an append-only history of approval changes stored next to the approvals
+type AuditLog struct {
+	Value []AuditEntry
+}
+
+func auditKey(approvalKey string) string {
+	return approvalKey + ".audit"
+}
+
+func (l *AuditLog) Pull(approvalKey string) error {
+	return registry.Pull(defaultRegistry, auditKey(approvalKey), &l.Value)
+}
+
+func (l AuditLog) Push(approvalKey string) error {
+	return registry.Push(defaultRegistry, auditKey(approvalKey), l.Value)
+}
+
+func (l AuditLog) Find(filter AuditFilter) []AuditEntry {
+	var value = []AuditEntry{}
+	for _, entry := range l.Value {
+		if filter.match(entry) {
+			value = append(value, entry)
+		}
+	}
+	return value
+}
+
+func appendAudit(approvalKey string, entries ...AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var log = new(AuditLog)
+	err := log.Pull(approvalKey)
+	if err != nil && !errors.Is(err, registry.ErrNoSuchKey) {
+		return errors.Join(ErrPullAudit, err)
+	}
+	log.Value = append(log.Value, entries...)
+	if err = log.Push(approvalKey); err != nil {
+		return errors.Join(ErrPushAudit, err)
+	}
+	return nil
+}
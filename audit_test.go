@@ -0,0 +1,78 @@
+package gosnap
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ecwid/gosnap/registry"
+	"github.com/ecwid/gosnap/registry/registrytest"
+)
+
+func TestAuditLog(t *testing.T) {
+	SetRegistry(newMemRegistry())
+	synced := NewSyncedOps()
+	approval := Approval{Hash: hashString("1"), Approver: "u1", Key: "page", Comment: "new banner"}
+
+	if err := synced.AcceptApproval("approvals", approval); err != nil {
+		t.Fatal(err)
+	}
+	approval.Approver = "u2"
+	if err := synced.DeclineApproval("approvals", approval); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := synced.Audit("approvals", AuditFilter{Key: "page"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Action != AuditAccept || entries[1].Action != AuditDecline {
+		t.Error("expected accept and decline entries", entries)
+	}
+	if entries[0].Comment != "new banner" {
+		t.Error("comment not recorded", entries[0])
+	}
+	entries, _ = synced.Audit("approvals", AuditFilter{User: "u2"})
+	if len(entries) != 1 || entries[0].Action != AuditDecline {
+		t.Error("expected decline entry of u2", entries)
+	}
+	entries, _ = synced.Audit("approvals", AuditFilter{From: time.Now().Add(time.Hour)})
+	if len(entries) != 0 {
+		t.Error("expected no entries in the future", entries)
+	}
+}
+
+type failingAudit struct {
+	*registrytest.Registry
+}
+
+func (r failingAudit) Push(key string, value registry.Object) error {
+	if strings.HasSuffix(key, ".audit") {
+		return errors.New("audit is read-only")
+	}
+	return r.Registry.Push(key, value)
+}
+
+func TestAuditFirst(t *testing.T) {
+	SetRegistry(failingAudit{newMemRegistry()})
+	synced := NewSyncedOps()
+	if err := synced.Accept("approvals", hashString("1"), "u1"); !errors.Is(err, ErrPushAudit) {
+		t.Fatal("expected audit error", err)
+	}
+	if err := new(Approvals).Pull("approvals"); !errors.Is(err, registry.ErrNoSuchKey) {
+		t.Error("expected no approval without audit record", err)
+	}
+}
+
+func TestDeclineHashUser(t *testing.T) {
+	SetRegistry(newMemRegistry())
+	synced := NewSyncedOps()
+	_ = synced.Accept("approvals", hashString("1"), "u1")
+	if err := synced.DeclineHash("approvals", hashString("1"), "u2"); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := synced.Audit("approvals", AuditFilter{User: "u2"}); len(entries) != 1 || entries[0].Action != AuditDecline {
+		t.Error("expected decline entry of u2", entries)
+	}
+}
@@ -20,6 +20,7 @@ type Change struct {
 	Data       map[string]string `json:"data"`
 	Target     string            `json:"target"`
 	Overlay    string            `json:"overlay"`
+	Approvals  []Approval        `json:"approvals,omitempty"`
 
 	target       image.Image `json:"-"`
 	approveLabel string      `json:"-"`
@@ -29,7 +30,53 @@ func (e Change) GetApproveUrl() string {
 	return KeyToApproveUrl(e.approveLabel, e.Key)
 }
 
+// Approval returns an approval of the change scoped to its baseline key and target hash
+func (e Change) Approval(approver string) Approval {
+	target := e.TargetHash
+	return Approval{
+		Hash:     e.XorHash,
+		Approver: approver,
+		Key:      e.Key,
+		Target:   &target,
+	}
+}
+
+// Is matches ErrApproved for an auto-approved change and ErrChanged otherwise
+func (e Change) Is(target error) bool {
+	if e.Approved() {
+		return target == ErrApproved
+	}
+	return target == ErrChanged
+}
+
+// As also supports *Change targets
+func (e Change) As(target any) bool {
+	if value, ok := target.(**Change); ok {
+		*value = &e
+		return true
+	}
+	return false
+}
+
+// Approved reports whether the change was auto-approved
+func (e Change) Approved() bool {
+	return len(e.Approvals) > 0
+}
+
 func (e Change) Error() string {
+	if e.Approved() {
+		s := fmt.Sprintf(`
+	the page changed (score %d) and was auto-approved
+	expected:   %s
+	`,
+			e.XorHash.onesCount(),
+			defaultRegistry.Resolve(e.Key),
+		)
+		for _, approval := range e.Approvals {
+			s += approval.String() + "\n"
+		}
+		return s
+	}
 	s := fmt.Sprintf(`
 	the page changed (score %d)
 	expected:   %s
@@ -68,7 +115,7 @@ func (batch Batch) findIndex(key string) int {
 	return -1
 }
 
-func addChanges(key string, target Change) error {
+func addChanges(key string, targets ...Change) error {
 
 	var batch = new(Batch)
 	err := batch.Pull(key)
@@ -76,20 +123,16 @@ func addChanges(key string, target Change) error {
 		return err
 	}
 
-	target.Ts = getUnixTs()
+	for _, target := range targets {
+		target.Ts = getUnixTs()
 
-	// update
-	updated := false
-	for n := range batch.Changes {
-		if batch.Changes[n].Key == target.Key {
+		// update
+		if n := batch.findIndex(target.Key); n >= 0 {
 			batch.Changes[n] = target
-			updated = true
-			break
+			continue
 		}
-	}
 
-	// a new one
-	if !updated {
+		// a new one
 		batch.Changes = append(batch.Changes, target)
 	}
 
@@ -114,3 +157,41 @@ func deleteChanges(key string, changeKey *string) error {
 
 	return batch.Push(key)
 }
+
+func promoteChange(change Change, author string, bits uint, revisions int) error {
+	var snapshot = new(Snapshot)
+	if err := snapshot.Pull(change.Target); err != nil {
+		return err
+	}
+	if err := keepRevision(change.Key, revisions); err != nil {
+		return err
+	}
+	snapshot.Hash = MakeHash(snapshot.Value, bits)
+	snapshot.Metadata["author"] = author
+	return snapshot.Push(change.Key)
+}
+
+func promoteChanges(key, author string, bits uint, revisions int, filter func(Change) bool) error {
+	var batch = new(Batch)
+	if err := batch.Pull(key); err != nil {
+		return err
+	}
+
+	var (
+		rest = []Change{}
+		errs error
+	)
+	for _, change := range batch.Changes {
+		if filter != nil && !filter(change) {
+			rest = append(rest, change)
+			continue
+		}
+		if err := promoteChange(change, author, bits, revisions); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("can't promote %s: %w", change.Key, err))
+			rest = append(rest, change)
+		}
+	}
+
+	batch.Changes = rest
+	return errors.Join(errs, batch.Push(key))
+}
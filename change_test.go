@@ -0,0 +1,124 @@
+package gosnap
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestPromoteChanges(t *testing.T) {
+	SetRegistry(newMemRegistry())
+	matcher := NewMatcher("run").ApprovalEnabled(false, "")
+
+	var published Published
+	err := matcher.New("page").Compare(testImage(64, 64, 16))
+	if !errors.As(err, &published) {
+		t.Fatal("baseline not published", err)
+	}
+	target := testImage(64, 64, 48)
+	if _, ok := matcher.New("page").Compare(target).(Change); !ok {
+		t.Fatal("change expected")
+	}
+
+	if err = matcher.PromoteChanges("qa", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = matcher.New("page").Compare(target); err != nil {
+		t.Error("promoted baseline doesn't match target", err)
+	}
+
+	baseline := new(Snapshot)
+	if err = baseline.Head("page"); err != nil {
+		t.Fatal(err)
+	}
+	if baseline.Metadata["author"] != "qa" {
+		t.Error("author not recorded", baseline.Metadata)
+	}
+	batch := new(Batch)
+	if err = batch.Pull("run"); err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.Changes) != 0 {
+		t.Error("batch not cleared", batch.Changes)
+	}
+}
+
+func TestQueryResult(t *testing.T) {
+	SetRegistry(newMemRegistry())
+	matcher := NewMatcher("run").ApprovalEnabled(true, "approvals")
+
+	result, err := matcher.New("page").Result(testImage(64, 64, 16))
+	if err != nil || result.Status != StatusNew {
+		t.Fatal("expected new baseline", result, err)
+	}
+	result, err = matcher.New("page").Result(testImage(64, 64, 16))
+	if err != nil || result.Status != StatusMatched {
+		t.Fatal("expected match", result, err)
+	}
+	result, err = matcher.New("page").Result(testImage(64, 64, 48))
+	if err != nil || result.Status != StatusChanged || result.Change.Target == "" {
+		t.Fatal("expected uploaded change", result, err)
+	}
+	if err = matcher.Synced().AcceptApproval("approvals", result.Change.Approval("qa")); err != nil {
+		t.Fatal(err)
+	}
+	result, err = matcher.New("page").Result(testImage(64, 64, 48))
+	if err != nil || result.Status != StatusApproved || len(result.Approvals) != 1 {
+		t.Fatal("expected approved change", result, err)
+	}
+	if err = matcher.New("page").Compare(testImage(64, 64, 48)); err != nil {
+		t.Error("approved change must not fail Compare", err)
+	}
+
+	var log bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&log, nil)))
+	if err = matcher.ReportApproved(true).New("page").Compare(testImage(64, 64, 48)); err != nil {
+		t.Error("reported approved change must not fail Compare", err)
+	}
+	if !strings.Contains(log.String(), "approved by qa") {
+		t.Error("expected approval logged", log.String())
+	}
+}
+
+func TestBufferChanges(t *testing.T) {
+	SetRegistry(newMemRegistry())
+	matcher := NewMatcher("run").ApprovalEnabled(false, "").BufferChanges(true)
+	for _, key := range []string{"a", "b"} {
+		_ = matcher.New(key).Compare(testImage(64, 64, 16))
+		if err := matcher.New(key).Compare(testImage(64, 64, 48)); !errors.Is(err, ErrChanged) {
+			t.Fatal("change expected", err)
+		}
+	}
+
+	batch := new(Batch)
+	if err := batch.Pull("run"); err == nil {
+		t.Error("batch must not be written before flush", batch.Changes)
+	}
+	if err := matcher.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Pull("run"); err != nil || len(batch.Changes) != 2 {
+		t.Error("expected two changes after flush", batch.Changes, err)
+	}
+}
+
+func TestContentAddressed(t *testing.T) {
+	SetRegistry(newMemRegistry())
+	matcher := NewMatcher("run").ApprovalEnabled(false, "").ContentAddressed(true).SnapshotSource("2023")
+	_ = matcher.New("page").Compare(testImage(64, 64, 16))
+
+	var first, second Change
+	if !errors.As(matcher.New("page").Compare(testImage(64, 64, 48)), &first) ||
+		!errors.As(matcher.New("page").Compare(testImage(64, 64, 48)), &second) {
+		t.Fatal("changes expected")
+	}
+	if first.Target != second.Target || first.Overlay != second.Overlay {
+		t.Error("identical snapshots must share keys", first.Target, second.Target)
+	}
+	if !strings.HasPrefix(first.Target, "2023/") || len(first.Target) != len("2023/")+64 {
+		t.Error("expected digest key under snapshot source", first.Target)
+	}
+}
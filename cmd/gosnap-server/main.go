@@ -0,0 +1,40 @@
+// Command gosnap-server is a reference snapshot store for the HTTP registry keeping objects on disk
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ecwid/gosnap/registry/disk"
+	"github.com/ecwid/gosnap/registry/httpreg"
+)
+
+func main() {
+	var (
+		addr      = flag.String("addr", "127.0.0.1:8080", "listen address, like :8080 to listen on all interfaces")
+		dir       = flag.String("dir", "snapshots", "directory to store objects in")
+		token     = flag.String("token", os.Getenv("GOSNAP_TOKEN"), "bearer token required from clients, $GOSNAP_TOKEN")
+		anonymous = flag.Bool("anonymous", false, "allow running without a token, anyone reaching the server can overwrite objects")
+		timeout   = flag.Duration("timeout", time.Minute, "read and write timeout of a request")
+	)
+	flag.Parse()
+	if *token == "" && !*anonymous {
+		fmt.Fprintln(os.Stderr, "gosnap-server: -token or $GOSNAP_TOKEN is required, pass -anonymous to run without one")
+		os.Exit(2)
+	}
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           httpreg.NewHandler(disk.NewRegistry(*dir, ""), *token),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       *timeout,
+		WriteTimeout:      *timeout,
+		IdleTimeout:       2 * time.Minute,
+	}
+	log.Printf("gosnap-server listening on %s, storing in %s", *addr, *dir)
+	log.Fatal(server.ListenAndServe())
+}
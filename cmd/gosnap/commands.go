@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image/png"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ecwid/gosnap"
+)
+
+func arg(fs *flag.FlagSet, n int) string {
+	if fs.NArg() <= n {
+		fs.Usage()
+		os.Exit(2)
+	}
+	return fs.Arg(n)
+}
+
+func compare(args []string) error {
+	var c config
+	fs := newFlagSet("compare", "<key> <png>", &c)
+	_ = fs.Parse(args)
+	key, name := arg(fs, 0), arg(fs, 1)
+	if err := c.open(); err != nil {
+		return err
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		return err
+	}
+	result, err := c.matcher().New(key).Result(img)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s %s (distance %d)\n", result.Status, result.Key, result.Distance)
+	if result.Change != nil {
+		fmt.Print(result.Change.Error())
+	}
+	if result.Status == gosnap.StatusChanged {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func findChange(c config, key string) (gosnap.Change, error) {
+	var batch = new(gosnap.Batch)
+	if err := batch.Pull(c.run); err != nil {
+		return gosnap.Change{}, err
+	}
+	for _, change := range batch.Changes {
+		if change.Key == key || change.Key == c.baselineKey(key) {
+			return change, nil
+		}
+	}
+	return gosnap.Change{}, fmt.Errorf("no change of %s in run %s", key, c.run)
+}
+
+type approvalFlags struct {
+	comment, ticket, role *string
+	expires               *time.Duration
+}
+
+func newApprovalFlags(fs *flag.FlagSet) approvalFlags {
+	return approvalFlags{
+		comment: fs.String("comment", "", "why the change is approved"),
+		ticket:  fs.String("ticket", "", "issue reference"),
+		role:    fs.String("role", "", "approver role"),
+		expires: fs.Duration("expires", 0, "approval lifetime, the matcher's ttl if zero"),
+	}
+}
+
+// approval of the key's change in the run batch
+func (f approvalFlags) approval(c config, key string) (gosnap.Approval, error) {
+	if c.approvals == "" || c.run == "" {
+		return gosnap.Approval{}, errors.New("approvals key and run id are required")
+	}
+	if err := c.open(); err != nil {
+		return gosnap.Approval{}, err
+	}
+	change, err := findChange(c, key)
+	if err != nil {
+		return gosnap.Approval{}, err
+	}
+	value := change.Approval(c.user)
+	value.Comment = *f.comment
+	value.Ticket = *f.ticket
+	value.Role = *f.role
+	if *f.expires > 0 {
+		value.Expires = time.Now().Add(*f.expires).Unix()
+	}
+	return value, nil
+}
+
+func approve(args []string) error {
+	var c config
+	fs := newFlagSet("approve", "<key>", &c)
+	flags := newApprovalFlags(fs)
+	_ = fs.Parse(args)
+	value, err := flags.approval(c, arg(fs, 0))
+	if err != nil {
+		return err
+	}
+	return c.synced().AcceptApproval(c.approvals, value)
+}
+
+func decline(args []string) error {
+	var c config
+	fs := newFlagSet("decline", "<key> | -hash <hash>", &c)
+	flags := newApprovalFlags(fs)
+	hash := fs.String("hash", "", "decline approvals of the diff hash whatever their scope, the change needn't be in the run")
+	_ = fs.Parse(args)
+	if *hash != "" {
+		if c.approvals == "" {
+			return errors.New("approvals key is required")
+		}
+		if err := c.open(); err != nil {
+			return err
+		}
+		return c.synced().DeclineHash(c.approvals, gosnap.HashString(*hash), c.user)
+	}
+	value, err := flags.approval(c, arg(fs, 0))
+	if err != nil {
+		return err
+	}
+	return c.synced().DeclineApproval(c.approvals, value)
+}
+
+func changes(args []string) error {
+	var c config
+	fs := newFlagSet("changes", "list|clear [key]", &c)
+	_ = fs.Parse(args)
+	command := fs.Arg(0)
+	if command == "" {
+		command = "list"
+	}
+	if command != "list" && command != "clear" {
+		fs.Usage()
+		return errors.New("unknown changes command")
+	}
+	if err := c.open(); err != nil {
+		return err
+	}
+	if command == "list" {
+		var batch = new(gosnap.Batch)
+		if err := batch.Pull(c.run); err != nil {
+			return err
+		}
+		for _, change := range batch.Changes {
+			fmt.Printf("%s\t%s", change.Key, change.Error())
+		}
+		return nil
+	}
+	var key *string
+	if fs.NArg() > 1 {
+		value := c.baselineKey(fs.Arg(1))
+		key = &value
+	}
+	return c.synced().DeleteChanges(c.run, key)
+}
+
+func promote(args []string) error {
+	var c config
+	fs := newFlagSet("promote", "[key...]", &c)
+	_ = fs.Parse(args)
+	if err := c.open(); err != nil {
+		return err
+	}
+	var filter func(gosnap.Change) bool
+	if fs.NArg() > 0 {
+		keys := map[string]bool{}
+		for _, key := range fs.Args() {
+			keys[c.baselineKey(key)] = true
+		}
+		filter = func(change gosnap.Change) bool {
+			return keys[change.Key]
+		}
+	}
+	return c.matcher().PromoteChanges(c.user, filter)
+}
+
+func baseline(args []string) error {
+	var c config
+	fs := newFlagSet("baseline", "show|history|rollback <key>", &c)
+	ts := fs.Int64("ts", 0, "unix time of the revision to roll back to, the latest if zero")
+	_ = fs.Parse(args)
+	command, key := fs.Arg(0), c.baselineKey(arg(fs, 1))
+	if command != "show" && command != "history" && command != "rollback" {
+		fs.Usage()
+		return errors.New("unknown baseline command")
+	}
+	if err := c.open(); err != nil {
+		return err
+	}
+	synced := c.synced()
+
+	switch command {
+	case "show":
+		var snapshot = new(gosnap.Snapshot)
+		if err := snapshot.Head(key); err != nil {
+			return err
+		}
+		x, y := snapshot.GetSize()
+		fmt.Printf("%s %dx%d hash %s\n", key, x, y, snapshot.Hash)
+		keys := make([]string, 0, len(snapshot.Metadata))
+		for k := range snapshot.Metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("  %s: %s\n", k, snapshot.Metadata[k])
+		}
+		return nil
+	case "history":
+		revisions, err := synced.History(key)
+		if err != nil {
+			return err
+		}
+		for _, revision := range revisions {
+			fmt.Printf("%d\t%s\t%s\t%s\n", revision.Ts, time.Unix(revision.Ts, 0).UTC().Format(time.DateTime), revision.Author, revision.Snapshot)
+		}
+		return nil
+	}
+	return synced.Rollback(key, *ts, c.user)
+}
+
+func hash(args []string) error {
+	fs := flag.NewFlagSet("hash", flag.ExitOnError)
+	bits := fs.Uint("bits", 1024, "hash size")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: gosnap hash [flags] <png>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	file, err := os.Open(arg(fs, 0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	img, err := png.Decode(file)
+	if err != nil {
+		return err
+	}
+	value := gosnap.MakeHash(img, *bits)
+	fmt.Println(value)
+	fmt.Print(value.SquareString(int(math.Sqrt(float64(*bits)))))
+	return nil
+}
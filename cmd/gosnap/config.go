@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ecwid/gosnap"
+)
+
+type config struct {
+	registry  string
+	run       string
+	approvals string
+	source    string
+	user      string
+	history   int
+}
+
+func env(keys ...string) string {
+	for _, key := range keys {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func newFlagSet(name, args string, c *config) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(&c.registry, "registry", env("GOSNAP_REGISTRY"), "registry, $GOSNAP_REGISTRY")
+	fs.StringVar(&c.run, "run", env("GOSNAP_RUN"), "run id of the change batch, $GOSNAP_RUN")
+	fs.StringVar(&c.approvals, "approvals", env("GOSNAP_APPROVALS"), "approvals key, $GOSNAP_APPROVALS")
+	fs.StringVar(&c.source, "source", env("GOSNAP_SOURCE"), "comma separated snapshot source path, $GOSNAP_SOURCE")
+	fs.StringVar(&c.user, "user", env("GOSNAP_USER", "USER"), "approver and author name, $GOSNAP_USER")
+	fs.IntVar(&c.history, "history", 0, "number of replaced baselines kept as revisions, none if zero")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: gosnap %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), registryUsage)
+	}
+	return fs
+}
+
+func (c config) open() error {
+	r, err := openRegistry(c.registry)
+	if err != nil {
+		return err
+	}
+	gosnap.SetRegistry(r)
+	return nil
+}
+
+func (c config) matcher() gosnap.Matcher {
+	m := gosnap.NewMatcher(c.run).ApprovalEnabled(c.approvals != "", c.approvals).KeepHistory(c.history)
+	if c.source != "" {
+		m = m.SnapshotSource(strings.Split(c.source, ",")...)
+	}
+	return m
+}
+
+func (c config) synced() gosnap.Synced {
+	return c.matcher().Synced()
+}
+
+// baselineKey prepends the snapshot source like queries do
+func (c config) baselineKey(key string) string {
+	if c.source == "" {
+		return key
+	}
+	return strings.ReplaceAll(c.source, ",", "/") + "/" + key
+}
@@ -0,0 +1,52 @@
+// Command gosnap manages snapshots, approvals and change batches stored in a registry
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+var commands = map[string]func(args []string) error{
+	"compare":  compare,
+	"approve":  approve,
+	"decline":  decline,
+	"changes":  changes,
+	"promote":  promote,
+	"baseline": baseline,
+	"hash":     hash,
+	"migrate":  migrate,
+	"report":   report,
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: gosnap <command> [flags]
+
+commands:
+  compare   match a png with the baseline
+  approve   approve a change of the run
+  decline   decline an approved change of the run
+  changes   list or clear changes of the run
+  promote   make targets of the run's changes baselines
+  baseline  show a baseline, its history or roll it back
+  hash      print the hash of a png
+  migrate   copy everything from one registry to another
+  report    write an html report of the run's changes
+
+registry and keys are configured with flags or GOSNAP_* environment variables,
+see gosnap <command> -h`)
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+	}
+	if err := command(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "gosnap:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+
+	"github.com/ecwid/gosnap"
+	"github.com/ecwid/gosnap/registry/disk"
+)
+
+func testImage(stripe int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 64; y++ {
+			img.Set(x, y, color.White)
+			if x < stripe {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	return img
+}
+
+// newRun stores a baseline of page and its change in the batch of run
+func newRun(t *testing.T) []string {
+	dir := t.TempDir()
+	gosnap.SetRegistry(disk.NewRegistry(dir, ""))
+	matcher := gosnap.NewMatcher("run").ApprovalEnabled(true, "approvals")
+	_ = matcher.New("page").Compare(testImage(16))
+	if err := matcher.New("page").Compare(testImage(48)); !errors.Is(err, gosnap.ErrChanged) {
+		t.Fatal("expected change", err)
+	}
+	return []string{"-registry", "dir:" + dir, "-run", "run", "-approvals", "approvals", "-user", "qa"}
+}
+
+func approvals(t *testing.T) []gosnap.Approval {
+	var value = new(gosnap.Approvals)
+	if err := value.Pull("approvals"); err != nil {
+		t.Fatal(err)
+	}
+	return value.Value
+}
+
+func TestOpenRegistry(t *testing.T) {
+	for _, spec := range []string{"dir:" + t.TempDir(), "git:" + t.TempDir(), "http://localhost/snapshots"} {
+		if _, err := openRegistry(spec); err != nil {
+			t.Error(spec, err)
+		}
+	}
+	if _, err := openRegistry(filepath.Join("no", "scheme")); err == nil {
+		t.Error("expected unknown registry error")
+	}
+}
+
+func TestApproveDecline(t *testing.T) {
+	flags := newRun(t)
+	if err := approve(append(flags, "-comment", "new header", "page")); err != nil {
+		t.Fatal(err)
+	}
+	values := approvals(t)
+	if len(values) != 1 || values[0].Approver != "qa" || values[0].Comment != "new header" {
+		t.Fatal("expected approval", values)
+	}
+	if err := decline(append(flags, "page")); err != nil {
+		t.Fatal(err)
+	}
+	if values = approvals(t); len(values) != 0 {
+		t.Error("expected approval declined", values)
+	}
+}
+
+func TestDeclineHash(t *testing.T) {
+	flags := newRun(t)
+	if err := approve(append(flags, "page")); err != nil {
+		t.Fatal(err)
+	}
+	hash := approvals(t)[0].Hash.String()
+	if err := changes(append(flags, "clear")); err != nil {
+		t.Fatal(err)
+	}
+	if err := decline(append(flags, "page")); err == nil {
+		t.Error("expected missing change error")
+	}
+	if err := decline(append(flags, "-hash", hash)); err != nil {
+		t.Fatal(err)
+	}
+	if values := approvals(t); len(values) != 0 {
+		t.Error("expected approval declined by hash", values)
+	}
+}
+
+func TestChangesCommand(t *testing.T) {
+	flags := newRun(t)
+	if err := changes(append(flags, "list")); err != nil {
+		t.Error(err)
+	}
+	if err := changes(flags); err != nil {
+		t.Error("expected list by default", err)
+	}
+	if err := changes(append(flags, "purge")); err == nil {
+		t.Error("expected unknown command error")
+	}
+}
+
+func TestChangesClearKey(t *testing.T) {
+	flags := newRun(t)
+	if err := changes(append(flags, "clear", "other")); err != nil {
+		t.Fatal(err)
+	}
+	var batch = new(gosnap.Batch)
+	if err := batch.Pull("run"); err != nil || len(batch.Changes) != 1 {
+		t.Fatal("expected the change of page kept", batch.Changes, err)
+	}
+	if err := changes(append(flags, "clear", "page")); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Pull("run"); err != nil || len(batch.Changes) != 0 {
+		t.Error("expected the change of page cleared", batch.Changes, err)
+	}
+}
+
+func TestPromoteHistory(t *testing.T) {
+	flags := newRun(t)
+	if err := promote(append(flags, "-history", "5")); err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := gosnap.NewSyncedOps().History("page")
+	if err != nil || len(revisions) != 1 {
+		t.Fatal("expected the replaced baseline kept", revisions, err)
+	}
+	if err = baseline(append(flags, "-ts", "0", "rollback", "page")); err != nil {
+		t.Fatal(err)
+	}
+	if err = gosnap.NewMatcher("").ApprovalEnabled(false, "").New("page").Compare(testImage(16)); err != nil {
+		t.Error("expected the first baseline restored", err)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/ecwid/gosnap"
+)
+
+type list []string
+
+func (l *list) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *list) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func migrate(args []string) error {
+	var (
+		fs        = flag.NewFlagSet("migrate", flag.ExitOnError)
+		from      = fs.String("from", "", "source registry")
+		to        = fs.String("to", "", "destination registry")
+		prefix    = fs.String("prefix", "", "migrate keys with the prefix only")
+		progress  = fs.String("progress", "", "file of migrated keys to resume an interrupted migration from, one per destination")
+		verify    = fs.Bool("verify", true, "compare copied objects with the source")
+		rename    = fs.String("rename", "", "old=new key prefix replacement")
+		approvals list
+		runs      list
+		baselines list
+	)
+	fs.Var(&approvals, "approvals", "approvals key to start from if the source can't list keys, repeatable")
+	fs.Var(&runs, "run", "run id to start from if the source can't list keys, repeatable")
+	fs.Var(&baselines, "baseline", "baseline key to copy if the source can't list keys, repeatable")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: gosnap migrate -from registry -to registry [flags]")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), registryUsage)
+	}
+	_ = fs.Parse(args)
+
+	src, err := openRegistry(*from)
+	if err != nil {
+		return err
+	}
+	dest, err := openRegistry(*to)
+	if err != nil {
+		return err
+	}
+	opts := gosnap.MigrateOptions{
+		Prefix:    *prefix,
+		Approvals: approvals,
+		Runs:      runs,
+		Baselines: baselines,
+		Progress:  *progress,
+		Verify:    *verify,
+	}
+	if oldPrefix, newPrefix, ok := strings.Cut(*rename, "="); ok {
+		opts.Rename = func(key string) string {
+			if strings.HasPrefix(key, oldPrefix) {
+				return newPrefix + strings.TrimPrefix(key, oldPrefix)
+			}
+			return key
+		}
+	}
+
+	result, err := gosnap.Migrate(src, dest, opts)
+	fmt.Printf("copied %d, skipped %d\n", result.Copied, result.Skipped)
+	return err
+}
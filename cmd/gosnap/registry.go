@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/ecwid/gosnap/registry"
+	"github.com/ecwid/gosnap/registry/disk"
+	"github.com/ecwid/gosnap/registry/gitdir"
+	"github.com/ecwid/gosnap/registry/httpreg"
+	"github.com/ecwid/gosnap/registry/s3"
+)
+
+const registryUsage = `registry is one of
+  s3://bucket?region=eu-west-1   credentials from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY,
+                                 acl=public-read by default, empty acl= omits it
+  http(s)://host/path            token from GOSNAP_TOKEN
+  dir:path                       local directory
+  git:path                       directory of a git repository`
+
+// openRegistry creates a registry from its spec, see registryUsage
+func openRegistry(spec string) (registry.Abstract, error) {
+	switch {
+	case strings.HasPrefix(spec, "s3://"):
+		u, err := url.Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		opts := s3.Options{Region: u.Query().Get("region"), ACL: "public-read"}
+		if u.Query().Has("acl") {
+			opts.ACL = u.Query().Get("acl")
+		}
+		return s3.NewRegistryWithOptions(os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), u.Host, opts)
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		return httpreg.NewRegistry(spec, httpreg.Options{Token: os.Getenv("GOSNAP_TOKEN")}), nil
+	case strings.HasPrefix(spec, "dir:"):
+		return disk.NewRegistry(strings.TrimPrefix(spec, "dir:"), ""), nil
+	case strings.HasPrefix(spec, "git:"):
+		return gitdir.NewRegistry(strings.TrimPrefix(spec, "git:")), nil
+	}
+	return nil, fmt.Errorf("unknown registry %q\n%s", spec, registryUsage)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+
+	"github.com/ecwid/gosnap"
+)
+
+func report(args []string) error {
+	var (
+		c       config
+		filters list
+	)
+	fs := newFlagSet("report", "", &c)
+	output := fs.String("o", "", "html file to write, stdout by default")
+	title := fs.String("title", "", "page title, the run id by default")
+	fs.Var(&filters, "filter", "metadata key to filter changes by, repeatable, all keys by default")
+	_ = fs.Parse(args)
+	if err := c.open(); err != nil {
+		return err
+	}
+	w := os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	return gosnap.Report(c.run, w, gosnap.ReportOptions{Title: *title, Filters: filters})
+}
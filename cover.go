@@ -0,0 +1,112 @@
+package gosnap
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// ApprovalIndex finds approvals whose union explains a diff hash
+type ApprovalIndex struct {
+	approvals []Approval
+	byBit     map[int][]int
+}
+
+func NewApprovalIndex(approvals []Approval) ApprovalIndex {
+	index := ApprovalIndex{
+		approvals: approvals,
+		byBit:     map[int][]int{},
+	}
+	for n, approval := range approvals {
+		for _, bit := range approval.Hash.bitIndexes() {
+			index.byBit[bit] = append(index.byBit[bit], n)
+		}
+	}
+	return index
+}
+
+func (h Hash) bitIndexes() []int {
+	var value []int
+	for n, word := range h.value.Bits() {
+		for w := uint(word); w != 0; w &= w - 1 {
+			value = append(value, n*bits.UintSize+bits.TrailingZeros(w))
+		}
+	}
+	return value
+}
+
+func (h Hash) andNot(other Hash) Hash {
+	return Hash{value: big.NewInt(0).AndNot(h.value, other.value)}
+}
+
+// Cover returns approvals whose union differs from hash in no more than distance bits,
+// an empty result means the hash isn't approved
+func (index ApprovalIndex) Cover(hash Hash, distance int) []Approval {
+	var candidates []int
+	seen := map[int]bool{}
+	for _, bit := range hash.bitIndexes() {
+		for _, n := range index.byBit[bit] {
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+			// single approval
+			if hash.Equal(index.approvals[n].Hash, distance) {
+				return []Approval{index.approvals[n]}
+			}
+			// bits outside of the hash can't be compensated by other approvals
+			if index.approvals[n].Hash.andNot(hash).onesCount() <= distance {
+				candidates = append(candidates, n)
+			}
+		}
+	}
+
+	// greedy set cover: take the approval explaining most of the rest of the diff
+	var (
+		chosen []int
+		cover  = Zero
+	)
+	for {
+		best, bestGain := -1, 0
+		for _, n := range candidates {
+			added := index.approvals[n].Hash.andNot(cover)
+			gain := added.andNot(hash.andNot(cover)).onesCount()
+			gain = added.onesCount() - 2*gain
+			if gain > bestGain {
+				best, bestGain = n, gain
+			}
+		}
+		if best < 0 {
+			break
+		}
+		chosen = append(chosen, best)
+		cover = cover.Or(index.approvals[best].Hash)
+	}
+	if !hash.Equal(cover, distance) {
+		return []Approval{}
+	}
+
+	// drop approvals that became redundant
+	for n := 0; n < len(chosen); n++ {
+		rest := Zero
+		for m, a := range chosen {
+			if m != n {
+				rest = rest.Or(index.approvals[a].Hash)
+			}
+		}
+		if len(chosen) > 1 && hash.Equal(rest, distance) {
+			chosen = append(chosen[:n], chosen[n+1:]...)
+			n--
+		}
+	}
+
+	value := make([]Approval, 0, len(chosen))
+	for _, n := range chosen {
+		value = append(value, index.approvals[n])
+	}
+	return value
+}
+
+// ApprovalsContains returns approvals whose union explains the hash within distance
+func ApprovalsContains(approvals []Approval, hash Hash, distance int) []Approval {
+	return NewApprovalIndex(approvals).Cover(hash, distance)
+}
@@ -0,0 +1,43 @@
+package gosnap
+
+import (
+	"math/big"
+	"testing"
+)
+
+func bitsHash(bits ...int) Hash {
+	value := big.NewInt(0)
+	for _, bit := range bits {
+		value.SetBit(value, bit, 1)
+	}
+	return Hash{value: value}
+}
+
+func TestApprovalsContainsComposition(t *testing.T) {
+	approvals := []Approval{
+		{Hash: bitsHash(1, 2, 3, 4), Approver: "header"},
+		{Hash: bitsHash(100, 101, 102, 103), Approver: "footer"},
+		{Hash: bitsHash(200, 201, 202, 203), Approver: "sidebar"},
+		{Hash: bitsHash(1, 2, 300, 301, 302, 303), Approver: "unrelated"},
+	}
+	diff := bitsHash(1, 2, 3, 4, 100, 101, 102, 103, 200, 201, 202, 203)
+
+	matched := ApprovalsContains(approvals, diff, 0)
+	if len(matched) != 3 {
+		t.Fatal("expected header, footer and sidebar", matched)
+	}
+	for _, approval := range matched {
+		if approval.Approver == "unrelated" {
+			t.Error("unrelated approval matched", matched)
+		}
+	}
+
+	matched = ApprovalsContains(approvals, diff.Or(bitsHash(500, 501)), 1)
+	if len(matched) != 0 {
+		t.Error("diff outside of approvals must not be approved", matched)
+	}
+	matched = ApprovalsContains(approvals, bitsHash(1, 2, 3, 4, 5), 1)
+	if len(matched) != 1 || matched[0].Approver != "header" {
+		t.Error("expected single header approval", matched)
+	}
+}
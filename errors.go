@@ -0,0 +1,37 @@
+package gosnap
+
+import "errors"
+
+var (
+	// ErrNoTarget is returned by Match, Result and Compare for a nil target image
+	ErrNoTarget = errors.New("no target (actual) image set")
+	// ErrNoKey is returned by Match, Result and Compare for a query without baseline key
+	ErrNoKey = errors.New("baseline key is required")
+	// ErrNoApprovalKey is returned by Match, Result and Compare if approval is enabled without approval key
+	ErrNoApprovalKey = errors.New("approvalEnabled but approvalKey not defined")
+	// ErrPullApprovals is returned by Match, Result and Compare if approvals can't be pulled
+	ErrPullApprovals = errors.New("can't pull approvals")
+	// ErrAddChanges is returned by Result and Compare if a change can't be added to the run's batch
+	ErrAddChanges = errors.New("can't add changes for approval")
+	// ErrUploadSnapshot is returned if a baseline, target or overlay image can't be uploaded
+	ErrUploadSnapshot = errors.New("can't upload snapshot image")
+	// ErrPullSnapshot is returned by Snapshot.Head and Snapshot.Pull
+	ErrPullSnapshot = errors.New("can't pull snapshot")
+	// ErrPushSnapshot is returned by Snapshot.Push
+	ErrPushSnapshot = errors.New("can't push snapshot")
+	// ErrDecodeSnapshot is returned by Snapshot.Pull if the body isn't a png
+	ErrDecodeSnapshot = errors.New("can't decode snapshot png")
+	// ErrEncodeSnapshot is returned by Snapshot.Push if the image can't be encoded to png
+	ErrEncodeSnapshot = errors.New("can't encode snapshot png")
+	// ErrPullAudit is returned by approval operations if the audit log can't be pulled
+	ErrPullAudit = errors.New("can't pull audit log")
+	// ErrPushAudit is returned by approval operations if the audit log can't be pushed
+	ErrPushAudit = errors.New("can't push audit log")
+
+	// ErrChanged matches a Change that isn't approved with errors.Is
+	ErrChanged = errors.New("the page changed")
+	// ErrApproved matches an auto-approved Change with errors.Is
+	ErrApproved = errors.New("the page change is approved")
+	// ErrPublished matches a Published with errors.Is
+	ErrPublished = errors.New("snapshot published")
+)
@@ -0,0 +1,52 @@
+package gosnap
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestErrorsWrapped(t *testing.T) {
+	err := errors.Join(ErrAddChanges, Change{Key: "page", XorHash: Zero})
+	if !errors.Is(err, ErrChanged) || !errors.Is(err, ErrAddChanges) {
+		t.Error("expected ErrChanged and ErrAddChanges", err)
+	}
+	var change *Change
+	if !errors.As(err, &change) || change.Key != "page" {
+		t.Error("expected *Change", err)
+	}
+	approved := Change{Key: "page", Approvals: []Approval{{Approver: "qa"}}}
+	if errors.Is(approved, ErrChanged) || !errors.Is(approved, ErrApproved) {
+		t.Error("expected approved change to match ErrApproved only")
+	}
+	var published Published
+	if !errors.As(errors.Join(errors.New("other"), Published{Key: "page"}), &published) || published.Key != "page" {
+		t.Error("expected Published")
+	}
+
+	SetRegistry(newMemRegistry())
+	matcher := NewMatcher("run")
+	if err = matcher.New("page").Match(nil); !errors.Is(err, ErrNoTarget) {
+		t.Error("expected ErrNoTarget", err)
+	}
+	if err = matcher.New("").Match(image.NewGray(image.Rect(0, 0, 1, 1))); !errors.Is(err, ErrNoKey) {
+		t.Error("expected ErrNoKey", err)
+	}
+}
+
+func TestUploadChangeKeepsErrors(t *testing.T) {
+	SetRegistry(newMemRegistry())
+	matcher := NewMatcher("run").ApprovalEnabled(false, "")
+	_ = matcher.New("page").Compare(testImage(64, 64, 16))
+	changed := matcher.New("page").Match(testImage(64, 64, 48))
+
+	err := matcher.New("page").UploadChange(errors.Join(errors.New("browser crashed"), changed))
+	var change Change
+	if !errors.As(err, &change) || change.Target == "" {
+		t.Error("expected uploaded change", err)
+	}
+	if !strings.Contains(err.Error(), "browser crashed") {
+		t.Error("expected the other error kept", err)
+	}
+}
@@ -0,0 +1,58 @@
+// Package gosnaptest matches screenshots with baselines from go tests
+package gosnaptest
+
+import (
+	"flag"
+	"image"
+	"testing"
+
+	"github.com/ecwid/gosnap"
+)
+
+var update = flag.Bool("gosnap.update", false, "replace gosnap baselines with the actual images")
+
+// Key derives the baseline key from the test name
+func Key(t testing.TB, name string) string {
+	if name == "" {
+		return t.Name()
+	}
+	return t.Name() + "/" + name
+}
+
+// Buffered returns the matcher buffering changes until t finishes,
+// AssertMatch calls sharing it add the test's changes to the run's batch with a single write
+func Buffered(t testing.TB, matcher gosnap.Matcher) gosnap.Matcher {
+	matcher = matcher.BufferChanges(true)
+	t.Cleanup(func() {
+		if err := matcher.Flush(); err != nil {
+			t.Errorf("gosnap: %v", err)
+		}
+	})
+	return matcher
+}
+
+// AssertMatch compares img with the baseline keyed by the test and name and fails the test on change.
+// Changes of an unbuffered matcher are added to the run's batch one by one, see Buffered.
+func AssertMatch(t testing.TB, matcher gosnap.Matcher, name string, img image.Image) bool {
+	t.Helper()
+	if *update {
+		matcher = matcher.Update(true)
+	}
+
+	key := Key(t, name)
+	result, err := matcher.New(key).Result(img)
+	if err != nil {
+		t.Errorf("gosnap: %s: %v", key, err)
+		return false
+	}
+	switch result.Status {
+	case gosnap.StatusChanged:
+		t.Errorf("gosnap: %s: %v", key, *result.Change)
+		return false
+	case gosnap.StatusNew, gosnap.StatusUpdated:
+		t.Logf("gosnap: %s: %v", key, result.Err())
+	case gosnap.StatusApproved:
+		t.Logf("gosnap: %s: %v", key, *result.Change)
+	}
+	return true
+}
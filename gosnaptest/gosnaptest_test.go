@@ -0,0 +1,79 @@
+package gosnaptest
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/ecwid/gosnap"
+	"github.com/ecwid/gosnap/registry/disk"
+)
+
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Logf(format string, args ...any) {}
+
+func testImage(stripe int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			if x < stripe {
+				img.Set(x, y, color.Black)
+			} else {
+				img.Set(x, y, color.White)
+			}
+		}
+	}
+	return img
+}
+
+func TestKey(t *testing.T) {
+	if key := Key(t, "header"); key != "TestKey/header" {
+		t.Error("unexpected key", key)
+	}
+	if key := Key(t, ""); key != "TestKey" {
+		t.Error("unexpected key", key)
+	}
+}
+
+func TestAssertMatch(t *testing.T) {
+	gosnap.SetRegistry(disk.NewRegistry(t.TempDir(), ""))
+	matcher := gosnap.NewMatcher("run").ApprovalEnabled(false, "")
+
+	t.Run("buffered", func(t *testing.T) {
+		r := &recorder{TB: t}
+		buffered := Buffered(r, matcher)
+		if !AssertMatch(r, buffered, "a", testImage(16)) || !AssertMatch(r, buffered, "b", testImage(16)) {
+			t.Fatal("expected new baselines", r.errors)
+		}
+		if AssertMatch(r, buffered, "a", testImage(48)) || AssertMatch(r, buffered, "b", testImage(48)) || len(r.errors) != 2 {
+			t.Fatal("expected changes", r.errors)
+		}
+		var batch = new(gosnap.Batch)
+		if err := batch.Pull("run"); err == nil {
+			t.Error("expected changes kept until the test finishes", batch.Changes)
+		}
+	})
+	var batch = new(gosnap.Batch)
+	if err := batch.Pull("run"); err != nil || len(batch.Changes) != 2 {
+		t.Error("expected changes flushed on cleanup", batch.Changes, err)
+	}
+
+	t.Run("update", func(t *testing.T) {
+		*update = true
+		defer func() { *update = false }()
+		r := &recorder{TB: t}
+		_ = AssertMatch(r, matcher, "a", testImage(16))
+		if !AssertMatch(r, matcher, "a", testImage(48)) || len(r.errors) != 0 {
+			t.Error("expected baseline updated", r.errors)
+		}
+	})
+}
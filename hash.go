@@ -100,3 +100,8 @@ func (h *Hash) SquareString(sq int) string {
 	}
 	return s.String()
 }
+
+// OnesCount returns the number of set bits, for a xor hash it's the distance between hashes
+func (h Hash) OnesCount() int {
+	return h.onesCount()
+}
@@ -0,0 +1,126 @@
+package gosnap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ecwid/gosnap/registry"
+)
+
+var ErrNoRevision = errors.New("no such baseline revision")
+
+// Revision is a previous version of a baseline kept when it's replaced
+type Revision struct {
+	Ts       int64  `json:"ts"`
+	Snapshot string `json:"snapshot"`
+	Hash     Hash   `json:"hash"`
+	Author   string `json:"author,omitempty"`
+}
+
+type History struct {
+	Value []Revision
+}
+
+func historyKey(key string) string {
+	return key + ".history"
+}
+
+// revisionKey isn't an immutable one, registries like gitdir keep revisions along with baselines
+func revisionKey(key string) string {
+	return fmt.Sprintf("%s/%d", historyKey(key), time.Now().UnixNano())
+}
+
+// isHistoryKey reports whether the key is of a history or a revision snapshot
+func isHistoryKey(key string) bool {
+	return strings.HasSuffix(key, ".history") || strings.Contains(key, ".history/")
+}
+
+func (h *History) Pull(key string) error {
+	return registry.Pull(defaultRegistry, historyKey(key), &h.Value)
+}
+
+func (h History) Push(key string) error {
+	return registry.Push(defaultRegistry, historyKey(key), h.Value)
+}
+
+// keepRevision copies the current baseline under a revision key and records it in the history
+// of no more than max revisions, nothing is kept for non-positive max.
+// Snapshots of dropped revisions stay in the registry as it can't delete objects.
+func keepRevision(key string, max int) error {
+	if max <= 0 {
+		return nil
+	}
+	obj, err := defaultRegistry.Pull(key)
+	if errors.Is(err, registry.ErrNoSuchKey) {
+		return nil
+	}
+	if err != nil {
+		return errors.Join(ErrPullSnapshot, err)
+	}
+	snapshot := revisionKey(key)
+	if err = defaultRegistry.Push(snapshot, *obj); err != nil {
+		return errors.Join(ErrPushSnapshot, err)
+	}
+
+	var history = new(History)
+	if err = history.Pull(key); err != nil && !errors.Is(err, registry.ErrNoSuchKey) {
+		return err
+	}
+	history.Value = append(history.Value, Revision{
+		Ts:       getUnixTs(),
+		Snapshot: snapshot,
+		Hash:     hashString(obj.Data[dataHash]),
+		Author:   obj.Data["author"],
+	})
+	if len(history.Value) > max {
+		history.Value = history.Value[len(history.Value)-max:]
+	}
+	return history.Push(key)
+}
+
+// History returns previous versions of the baseline, the oldest first
+func (s Synced) History(key string) ([]Revision, error) {
+	var history = new(History)
+	err := s.Sync(func() error {
+		return history.Pull(key)
+	})
+	if err != nil && !errors.Is(err, registry.ErrNoSuchKey) {
+		return nil, err
+	}
+	return history.Value, nil
+}
+
+// Rollback restores the baseline revision kept at ts, zero ts restores the latest one.
+// The replaced baseline is kept in the history as well if it's enabled by KeepHistory.
+func (s Synced) Rollback(key string, ts int64, author string) error {
+	return s.Sync(func() error {
+		var history = new(History)
+		if err := history.Pull(key); err != nil {
+			return errors.Join(ErrNoRevision, err)
+		}
+		var revision *Revision
+		for n := len(history.Value) - 1; n >= 0; n-- {
+			if ts == 0 || history.Value[n].Ts == ts {
+				revision = &history.Value[n]
+				break
+			}
+		}
+		if revision == nil {
+			return ErrNoRevision
+		}
+		obj, err := defaultRegistry.Pull(revision.Snapshot)
+		if err != nil {
+			return errors.Join(ErrPullSnapshot, err)
+		}
+		if err = keepRevision(key, s.revisions); err != nil {
+			return err
+		}
+		obj.Data["author"] = author
+		if err = defaultRegistry.Push(key, *obj); err != nil {
+			return errors.Join(ErrPushSnapshot, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,82 @@
+package gosnap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ecwid/gosnap/registry/gitdir"
+)
+
+func TestHistoryRollback(t *testing.T) {
+	SetRegistry(newMemRegistry())
+	matcher := NewMatcher("run").ApprovalEnabled(false, "").KeepHistory(10)
+	_ = matcher.New("page").Compare(testImage(64, 64, 16))
+	if err := matcher.Update(true).New("page").Compare(testImage(64, 64, 48)); err == nil {
+		t.Fatal("expected updated baseline")
+	}
+
+	revisions, err := matcher.Synced().History("page")
+	if err != nil || len(revisions) != 1 {
+		t.Fatal("expected one revision", revisions, err)
+	}
+	if err = matcher.Synced().Rollback("page", 0, "qa"); err != nil {
+		t.Fatal(err)
+	}
+	if err = matcher.New("page").Compare(testImage(64, 64, 16)); err != nil {
+		t.Error("expected the first baseline restored", err)
+	}
+	if revisions, _ = matcher.Synced().History("page"); len(revisions) != 2 {
+		t.Error("expected rolled back baseline kept", revisions)
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	SetRegistry(newMemRegistry())
+	matcher := NewMatcher("run").ApprovalEnabled(false, "").Update(true)
+	for _, stripe := range []int{16, 48} {
+		_ = matcher.New("page").Compare(testImage(64, 64, stripe))
+	}
+	if revisions, _ := matcher.Synced().History("page"); len(revisions) != 0 {
+		t.Error("expected no history by default", revisions)
+	}
+
+	matcher = matcher.KeepHistory(2)
+	for _, stripe := range []int{16, 48, 16, 48} {
+		_ = matcher.New("page").Compare(testImage(64, 64, stripe))
+	}
+	revisions, _ := matcher.Synced().History("page")
+	if len(revisions) != 2 {
+		t.Fatal("expected history trimmed to 2 revisions", revisions)
+	}
+	for _, ops := range []Synced{NewSyncedOps(), NewSyncedOps(), matcher.Synced()} {
+		if err := ops.CopySnapshot("page", "copy", "qa"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if revisions, _ = NewSyncedOps().History("copy"); len(revisions) != 1 {
+		t.Error("expected the copy replaced with history kept once", revisions)
+	}
+}
+
+func TestHistoryGitdirClone(t *testing.T) {
+	dir := t.TempDir()
+	SetRegistry(gitdir.NewRegistry(dir))
+	matcher := NewMatcher("run").ApprovalEnabled(false, "").KeepHistory(10)
+	_ = matcher.New("page").Compare(testImage(64, 64, 16))
+	_ = matcher.Update(true).New("page").Compare(testImage(64, 64, 48))
+
+	// a fresh clone lacks the ignored objects
+	for _, name := range []string{"objects", "objects-index"} {
+		if err := os.RemoveAll(filepath.Join(dir, ".gosnap", name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	SetRegistry(gitdir.NewRegistry(dir))
+	if err := NewSyncedOps().Rollback("page", 0, "qa"); err != nil {
+		t.Fatal("expected the revision committed with the baseline", err)
+	}
+	if err := matcher.New("page").Compare(testImage(64, 64, 16)); err != nil {
+		t.Error("expected the first baseline restored", err)
+	}
+}
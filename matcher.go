@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
@@ -20,23 +21,36 @@ func SetRegistry(r registry.Abstract) {
 	defaultRegistry = r
 }
 
+// GetRegistry returns the registry passed to SetRegistry
+func GetRegistry() registry.Abstract {
+	return defaultRegistry
+}
+
 func getUnixTs() int64 {
 	return time.Now().Unix()
 }
 
 type Matcher struct {
-	runID           string
-	addChange       bool
-	approvalEnabled bool
-	update          bool
-	forceUpdate     bool
-	normalize       bool
-	approvalKey     string
-	distance        int
-	hashSize        uint
-	data            map[string]string
-	sync            Synced
-	path            []string
+	runID            string
+	addChange        bool
+	approvalEnabled  bool
+	update           bool
+	forceUpdate      bool
+	normalize        bool
+	strictApprovals  bool
+	reportApproved   bool
+	quorum           Quorum
+	buffer           *changeBuffer
+	workers          int
+	approvals        *Approvals
+	contentAddressed bool
+	approvalKey      string
+	distance         int
+	hashSize         uint
+	approvalTTL      time.Duration
+	data             map[string]string
+	sync             Synced
+	path             []string
 }
 
 func NewMatcher(runID string) Matcher {
@@ -50,6 +64,8 @@ func NewMatcher(runID string) Matcher {
 		normalize:       false,
 		distance:        6,
 		hashSize:        1024,
+		workers:         runtime.NumCPU(),
+		approvalTTL:     DefaultApprovalTTL,
 		sync:            NewSyncedOps(),
 		data:            map[string]string{},
 	}
@@ -70,6 +86,12 @@ func (m Matcher) ForceUpdate(enable bool) Matcher {
 	return m
 }
 
+// Concurrency sets the number of CompareAll workers
+func (m Matcher) Concurrency(workers int) Matcher {
+	m.workers = max(workers, 1)
+	return m
+}
+
 func (m Matcher) HashSize(bits uint) Matcher {
 	m.hashSize = bits
 	return m
@@ -86,11 +108,58 @@ func (m Matcher) ApprovalEnabled(enable bool, key string) Matcher {
 	return m
 }
 
+// StrictApprovals ignores legacy approvals that are not scoped to a baseline key
+func (m Matcher) StrictApprovals(enable bool) Matcher {
+	m.strictApprovals = enable
+	return m
+}
+
+// Quorum makes approvals effective only after sign off by the number of distinct approvers
+// and by every one of the roles
+func (m Matcher) Quorum(approvers int, roles ...string) Matcher {
+	m.quorum = Quorum{Approvers: approvers, Roles: roles}
+	return m
+}
+
+// ReportApproved makes Match and Compare log auto-approved changes with the matched approvals
+// to slog.Default, they still return nil for them, see also MatchResult.Approvals
+func (m Matcher) ReportApproved(enable bool) Matcher {
+	m.reportApproved = enable
+	return m
+}
+
+// ApprovalRetention sets how long approvals stay effective and how many of them are kept,
+// non-positive ttl never expires approvals
+func (m Matcher) ApprovalRetention(ttl time.Duration, max int) Matcher {
+	m.approvalTTL = ttl
+	m.sync = m.sync.Retention(ttl, max)
+	return m
+}
+
+// Synced returns registry operations sharing the matcher's lock and approval retention
+func (m Matcher) Synced() Synced {
+	return m.sync
+}
+
 func (m Matcher) Metadata(key string, value any) Matcher {
 	m.data[key] = fmt.Sprint(value)
 	return m
 }
 
+// ContentAddressed keys target and overlay snapshots by sha256 of their png instead of uuid,
+// so identical snapshots are uploaded once
+func (m Matcher) ContentAddressed(enable bool) Matcher {
+	m.contentAddressed = enable
+	return m
+}
+
+// KeepHistory keeps up to max replaced baselines as revisions when they're updated or promoted,
+// see Synced.History. It's disabled by default.
+func (m Matcher) KeepHistory(max int) Matcher {
+	m.sync = m.sync.KeepHistory(max)
+	return m
+}
+
 func (m Matcher) SnapshotSource(args ...string) Matcher {
 	m.path = append(m.path, args...)
 	return m
@@ -103,18 +172,85 @@ func (m Matcher) prependPathString() string {
 	return ""
 }
 
-func (m Matcher) addChangeForApproval(compareError error) error {
-	if err, ok := compareError.(Change); ok && m.addChange {
-		syncError := m.sync.Sync(func() error {
-			return addChanges(m.runID, err)
-		})
-		if syncError != nil {
-			return errors.Join(compareError, errors.New("can't add changes for approval"), syncError)
-		}
-		err.approveLabel = m.runID
-		return err
+func (m Matcher) addChangeForApproval(change Change) (Change, error) {
+	if !m.addChange {
+		return change, nil
+	}
+	if m.buffer != nil {
+		m.buffer.add(change)
+		change.approveLabel = m.runID
+		return change, nil
+	}
+	syncError := m.sync.Sync(func() error {
+		return addChanges(m.runID, change)
+	})
+	if syncError != nil {
+		return change, errors.Join(ErrAddChanges, syncError)
+	}
+	change.approveLabel = m.runID
+	return change, nil
+}
+
+type changeBuffer struct {
+	mu      sync.Mutex
+	changes []Change
+}
+
+func (b *changeBuffer) add(changes ...Change) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.changes = append(b.changes, changes...)
+}
+
+func (b *changeBuffer) take() []Change {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	changes := b.changes
+	b.changes = nil
+	return changes
+}
+
+// BufferChanges keeps changes in memory until Flush instead of rewriting the run's batch for each of them.
+// Matchers derived from the returned one share its buffer.
+func (m Matcher) BufferChanges(enable bool) Matcher {
+	m.buffer = nil
+	if enable {
+		m.buffer = &changeBuffer{}
+	}
+	return m
+}
+
+// Buffered reports whether changes are kept in memory until Flush
+func (m Matcher) Buffered() bool {
+	return m.buffer != nil
+}
+
+// Flush adds buffered changes to the run's batch with a single write
+func (m Matcher) Flush() error {
+	if m.buffer == nil {
+		return nil
+	}
+	changes := m.buffer.take()
+	if len(changes) == 0 {
+		return nil
+	}
+	err := m.sync.Sync(func() error {
+		return addChanges(m.runID, changes...)
+	})
+	if err != nil {
+		// keep them for the next attempt
+		m.buffer.add(changes...)
+		return errors.Join(ErrAddChanges, err)
 	}
-	return compareError
+	return nil
+}
+
+// PromoteChanges makes targets of the run's changes the new baselines and removes them from the batch.
+// A nil filter promotes all changes.
+func (m Matcher) PromoteChanges(author string, filter func(Change) bool) error {
+	return m.sync.Sync(func() error {
+		return promoteChanges(m.runID, author, m.hashSize, m.sync.revisions, filter)
+	})
 }
 
 func (m Matcher) generateKey() string {
@@ -151,7 +287,10 @@ func DefaultCompare(expected, actual image.Image) error {
 }
 
 type Synced struct {
-	value *sync.Mutex
+	value        *sync.Mutex
+	maxApprovals int
+	approvalTTL  time.Duration
+	revisions    int
 }
 
 func (s Synced) Sync(cb func() error) error {
@@ -162,39 +301,148 @@ func (s Synced) Sync(cb func() error) error {
 
 func NewSyncedOps() Synced {
 	return Synced{
-		value: &sync.Mutex{},
+		value:       &sync.Mutex{},
+		approvalTTL: DefaultApprovalTTL,
 	}
 }
 
+// Retention sets approvals lifetime and max count, non-positive max falls back to MaxApprovals
+func (s Synced) Retention(ttl time.Duration, max int) Synced {
+	s.approvalTTL = ttl
+	s.maxApprovals = max
+	return s
+}
+
+// KeepHistory keeps up to max baselines replaced by CopySnapshot and Rollback, non-positive max disables it
+func (s Synced) KeepHistory(max int) Synced {
+	s.revisions = max
+	return s
+}
+
+func (s Synced) getMaxApprovals() int {
+	if s.maxApprovals > 0 {
+		return s.maxApprovals
+	}
+	return MaxApprovals
+}
+
 func (s Synced) Accept(key string, hash Hash, approver string) error {
+	return s.AcceptApproval(key, Approval{Hash: hash, Approver: approver})
+}
+
+// AcceptApproval stores the approval, an existing one with the same hash and scope is replaced
+func (s Synced) AcceptApproval(key string, approval Approval) error {
 	return s.Sync(func() error {
 		var approvals = new(Approvals)
 		err := approvals.Pull(key)
 		if err != nil && !errors.Is(err, registry.ErrNoSuchKey) {
 			return err
 		}
-		approvals.accept(Approval{Hash: hash, Approver: approver})
+		evicted := approvals.accept(approval, s.getMaxApprovals(), s.approvalTTL)
+		entries := []AuditEntry{newAuditEntry(AuditAccept, approval)}
+		for _, value := range evicted {
+			entries = append(entries, newAuditEntry(AuditExpire, value))
+		}
+		// audit goes first so there's no approval without a record
+		if err = appendAudit(key, entries...); err != nil {
+			return err
+		}
 		return approvals.Push(key)
 	})
 }
 
+// Decline removes every approval of the hash, the audit log records AuditUnknownUser as the one who declined.
+//
+// Deprecated: use DeclineHash or DeclineApproval.
 func (s Synced) Decline(key string, hash Hash) error {
+	return s.DeclineHash(key, hash, AuditUnknownUser)
+}
+
+// DeclineHash removes every approval of the hash whatever baseline key or target it's scoped to
+func (s Synced) DeclineHash(key string, hash Hash, user string) error {
 	return s.Sync(func() error {
 		var approvals = new(Approvals)
 		if err := approvals.Pull(key); err != nil {
 			return err
 		}
-		approvals.decline(hash)
+		declined := approvals.declineHash(hash)
+		if len(declined) == 0 {
+			return nil
+		}
+		entries := []AuditEntry{}
+		for _, value := range declined {
+			value.Approver, value.Role, value.Comment = user, "", ""
+			entries = append(entries, newAuditEntry(AuditDecline, value))
+		}
+		if err := appendAudit(key, entries...); err != nil {
+			return err
+		}
 		return approvals.Push(key)
 	})
 }
 
+// DeclineApproval removes the approval with the same hash and scope,
+// approval's Approver and Comment are recorded in the audit log
+func (s Synced) DeclineApproval(key string, approval Approval) error {
+	return s.Sync(func() error {
+		var approvals = new(Approvals)
+		if err := approvals.Pull(key); err != nil {
+			return err
+		}
+		if !approvals.decline(approval) {
+			return nil
+		}
+		if err := appendAudit(key, newAuditEntry(AuditDecline, approval)); err != nil {
+			return err
+		}
+		return approvals.Push(key)
+	})
+}
+
+// PruneApprovals removes expired approvals and returns how many were removed
+func (s Synced) PruneApprovals(key string) (pruned int, err error) {
+	err = s.Sync(func() error {
+		var approvals = new(Approvals)
+		if err := approvals.Pull(key); err != nil {
+			return err
+		}
+		expired := approvals.prune(s.approvalTTL)
+		if pruned = len(expired); pruned == 0 {
+			return nil
+		}
+		entries := []AuditEntry{}
+		for _, value := range expired {
+			entries = append(entries, newAuditEntry(AuditExpire, value))
+		}
+		if err := appendAudit(key, entries...); err != nil {
+			return err
+		}
+		return approvals.Push(key)
+	})
+	return pruned, err
+}
+
+// Audit returns audit log entries of the approvals stored under key
+func (s Synced) Audit(key string, filter AuditFilter) ([]AuditEntry, error) {
+	var log = new(AuditLog)
+	err := s.Sync(func() error {
+		return log.Pull(key)
+	})
+	if err != nil && !errors.Is(err, registry.ErrNoSuchKey) {
+		return nil, err
+	}
+	return log.Find(filter), nil
+}
+
 func (s Synced) CopySnapshot(src, dest, author string) error {
 	return s.Sync(func() error {
 		var snapshot = new(Snapshot)
 		if err := snapshot.Pull(src); err != nil {
 			return err
 		}
+		if err := keepRevision(dest, s.revisions); err != nil {
+			return err
+		}
 		snapshot.Metadata["author"] = author
 		return snapshot.Push(dest)
 	})
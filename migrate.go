@@ -0,0 +1,298 @@
+package gosnap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ecwid/gosnap/registry"
+)
+
+var (
+	ErrVerify = errors.New("migrated object differs from the source")
+	// ErrEncryptedRename is returned if an object encrypted by registry/envelope is renamed,
+	// its key is authenticated with the body so migrate through envelope registries instead
+	ErrEncryptedRename = errors.New("can't rename an encrypted object")
+)
+
+type MigrateOptions struct {
+	// Prefix limits migrated keys if the source or a registry it wraps implements registry.Lister
+	Prefix string
+	// Approvals and Runs are keys of approvals and change batches to start from
+	// if the source can't list keys, baselines and snapshots referenced by batches are copied as well
+	Approvals []string
+	Runs      []string
+	// Baselines are copied with their history if the source can't list keys,
+	// baselines not referenced by any run aren't found otherwise
+	Baselines []string
+	// Rename maps source keys to destination ones, references in batches, approvals,
+	// audit logs and histories are rewritten too
+	Rename func(key string) string
+	// Progress is a file of migrated keys, an interrupted migration resumes from it
+	Progress string
+	// Verify pulls every copied object back and compares it with the source
+	Verify bool
+}
+
+type MigrateResult struct {
+	Copied  int
+	Skipped int
+}
+
+type migration struct {
+	src, dest registry.Abstract
+	opts      MigrateOptions
+	renaming  bool
+	done      map[string]bool
+	progress  *os.File
+	result    MigrateResult
+}
+
+// Migrate copies baselines, approvals, change batches and snapshots from src to dest
+func Migrate(src, dest registry.Abstract, opts MigrateOptions) (MigrateResult, error) {
+	m := &migration{src: src, dest: dest, opts: opts, renaming: opts.Rename != nil, done: map[string]bool{}}
+	if !m.renaming {
+		m.opts.Rename = func(key string) string { return key }
+	}
+	if opts.Progress != "" {
+		if err := m.openProgress(); err != nil {
+			return m.result, err
+		}
+		defer m.progress.Close()
+	}
+
+	if lister, ok := registry.AsLister(src); ok {
+		keys, err := lister.List(opts.Prefix)
+		if err != nil {
+			return m.result, err
+		}
+		for _, key := range keys {
+			if _, err = m.copy(key, false); err != nil {
+				return m.result, err
+			}
+		}
+		return m.result, nil
+	}
+
+	for _, key := range opts.Approvals {
+		for _, k := range []string{key, auditKey(key)} {
+			if _, err := m.copy(k, false); err != nil && !errors.Is(err, registry.ErrNoSuchKey) {
+				return m.result, err
+			}
+		}
+	}
+	for _, key := range opts.Baselines {
+		if _, err := m.copy(key, false); err != nil {
+			return m.result, err
+		}
+		if err := m.copyHistory(key); err != nil {
+			return m.result, err
+		}
+	}
+	for _, key := range opts.Runs {
+		changes, err := m.copy(key, true)
+		if err != nil {
+			return m.result, err
+		}
+		for _, change := range changes {
+			for _, k := range []string{change.Key, change.Target, change.Overlay} {
+				if _, err = m.copy(k, false); err != nil && !errors.Is(err, registry.ErrNoSuchKey) {
+					return m.result, err
+				}
+			}
+		}
+	}
+	return m.result, nil
+}
+
+// copyHistory copies revisions of the baseline and the history referencing them
+func (m *migration) copyHistory(key string) error {
+	var history = new(History)
+	err := registry.Pull(m.src, historyKey(key), &history.Value)
+	if errors.Is(err, registry.ErrNoSuchKey) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", historyKey(key), err)
+	}
+	for _, revision := range history.Value {
+		if _, err = m.copy(revision.Snapshot, false); err != nil && !errors.Is(err, registry.ErrNoSuchKey) {
+			return err
+		}
+	}
+	_, err = m.copy(historyKey(key), false)
+	return err
+}
+
+func (m *migration) openProgress() error {
+	file, err := os.OpenFile(m.opts.Progress, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		m.done[scanner.Text()] = true
+	}
+	if err = scanner.Err(); err != nil {
+		file.Close()
+		return err
+	}
+	m.progress = file
+	return nil
+}
+
+// copy returns changes of a copied batch so the caller can follow the references
+func (m *migration) copy(key string, follow bool) ([]Change, error) {
+	if key == "" {
+		return nil, nil
+	}
+	if m.done[key] && !follow {
+		m.result.Skipped++
+		return nil, nil
+	}
+	object, err := m.src.Pull(key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	if m.renaming && encrypted(object.Data) {
+		return nil, fmt.Errorf("%s: %w", key, ErrEncryptedRename)
+	}
+	changes, body, err := m.rewrite(object.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	if m.done[key] {
+		m.result.Skipped++
+		return changes, nil
+	}
+
+	dest := m.opts.Rename(key)
+	object.Body = body
+	delete(object.Data, "last-modified-unix")
+	if err = m.dest.Push(dest, *object); err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	if m.opts.Verify {
+		if err = m.verify(dest, *object); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	m.done[key] = true
+	m.result.Copied++
+	if m.progress != nil {
+		if _, err = fmt.Fprintln(m.progress, key); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+func (m *migration) verify(key string, expected registry.Object) error {
+	actual, err := m.dest.Pull(key)
+	if err != nil {
+		return err
+	}
+	if !sameBody(actual.Body, expected.Body) {
+		return ErrVerify
+	}
+	if actual.Data[dataHash] != expected.Data[dataHash] {
+		return errors.Join(ErrVerify, errors.New("hash metadata mismatch"))
+	}
+	return nil
+}
+
+// sameBody compares json bodies by their compacted form as registries like gitdir reformat them
+func sameBody(actual, expected []byte) bool {
+	if bytes.Equal(actual, expected) {
+		return true
+	}
+	var a, b bytes.Buffer
+	return json.Compact(&a, actual) == nil && json.Compact(&b, expected) == nil && bytes.Equal(a.Bytes(), b.Bytes())
+}
+
+// rewrite renames references in batches, approvals, audit logs and histories, other bodies are kept as is
+func (m *migration) rewrite(body []byte) ([]Change, []byte, error) {
+	var items []map[string]json.RawMessage
+	if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) || json.Unmarshal(body, &items) != nil || len(items) == 0 {
+		return nil, body, nil
+	}
+	_, isBatch := items[0]["xorhash"]
+	_, isApproval := items[0]["approver"]
+	_, isAudit := items[0]["action"]
+	_, isHistory := items[0]["snapshot"]
+	if !isBatch && !m.renaming {
+		return nil, body, nil
+	}
+
+	switch {
+	case isBatch:
+		var changes []Change
+		if err := json.Unmarshal(body, &changes); err != nil {
+			return nil, nil, err
+		}
+		original := append([]Change{}, changes...)
+		if !m.renaming {
+			return original, body, nil
+		}
+		for n := range changes {
+			changes[n].Key = m.rename(changes[n].Key)
+			changes[n].Target = m.rename(changes[n].Target)
+			changes[n].Overlay = m.rename(changes[n].Overlay)
+		}
+		body, err := json.Marshal(changes)
+		return original, body, err
+	case isApproval:
+		var approvals []Approval
+		if err := json.Unmarshal(body, &approvals); err != nil {
+			return nil, nil, err
+		}
+		for n := range approvals {
+			approvals[n].Key = m.rename(approvals[n].Key)
+		}
+		body, err := json.Marshal(approvals)
+		return nil, body, err
+	case isAudit:
+		var entries []AuditEntry
+		if err := json.Unmarshal(body, &entries); err != nil {
+			return nil, nil, err
+		}
+		for n := range entries {
+			entries[n].Key = m.rename(entries[n].Key)
+		}
+		body, err := json.Marshal(entries)
+		return nil, body, err
+	case isHistory:
+		var revisions []Revision
+		if err := json.Unmarshal(body, &revisions); err != nil {
+			return nil, nil, err
+		}
+		for n := range revisions {
+			revisions[n].Snapshot = m.rename(revisions[n].Snapshot)
+		}
+		body, err := json.Marshal(revisions)
+		return nil, body, err
+	}
+	return nil, body, nil
+}
+
+// encrypted reports whether the data has the envelope.DataEncryption field, some registries change its case
+func encrypted(data map[string]string) bool {
+	for k := range data {
+		if strings.EqualFold(k, "Gosnap-Encryption") {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *migration) rename(key string) string {
+	if strings.TrimSpace(key) == "" {
+		return key
+	}
+	return m.opts.Rename(key)
+}
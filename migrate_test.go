@@ -0,0 +1,132 @@
+package gosnap
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ecwid/gosnap/registry"
+	"github.com/ecwid/gosnap/registry/gitdir"
+)
+
+// unlisted hides List of the wrapped registry
+type unlisted struct {
+	registry.Abstract
+}
+
+func TestMigrate(t *testing.T) {
+	src := newMemRegistry()
+	SetRegistry(src)
+	matcher := NewMatcher("run").ApprovalEnabled(true, "approvals").SnapshotSource("2023")
+	_ = matcher.New("page").Compare(testImage(64, 64, 16))
+	var change Change
+	if !errors.As(matcher.New("page").Compare(testImage(64, 64, 48)), &change) {
+		t.Fatal("change expected")
+	}
+	if err := matcher.Synced().AcceptApproval("approvals", change.Approval("qa")); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := newMemRegistry()
+	result, err := Migrate(unlisted{src}, dest, MigrateOptions{
+		Approvals: []string{"approvals"},
+		Runs:      []string{"run"},
+		Rename:    func(key string) string { return "v2/" + key },
+		Progress:  filepath.Join(t.TempDir(), "progress"),
+		Verify:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// approvals, audit, batch, baseline, target and overlay
+	if result.Copied != 6 {
+		t.Error("unexpected number of copied objects", result)
+	}
+
+	SetRegistry(dest)
+	batch := new(Batch)
+	if err = batch.Pull("v2/run"); err != nil || len(batch.Changes) != 1 {
+		t.Fatal("expected migrated batch", batch, err)
+	}
+	if !strings.HasPrefix(batch.Changes[0].Target, "v2/2023/") {
+		t.Error("expected rewritten target reference", batch.Changes[0].Target)
+	}
+	snapshot := new(Snapshot)
+	if err = snapshot.Pull(batch.Changes[0].Target); err != nil {
+		t.Error("expected migrated target", err)
+	}
+	err = NewMatcher("run").ApprovalEnabled(true, "v2/approvals").SnapshotSource("v2", "2023").
+		New("page").Compare(testImage(64, 64, 48))
+	if err != nil {
+		t.Error("expected migrated approval to apply", err)
+	}
+}
+
+func TestMigrateGitdir(t *testing.T) {
+	src := newMemRegistry()
+	SetRegistry(src)
+	matcher := NewMatcher("run").ApprovalEnabled(true, "approvals")
+	_ = matcher.New("page").Compare(testImage(64, 64, 16))
+	var change Change
+	if !errors.As(matcher.New("page").Compare(testImage(64, 64, 48)), &change) {
+		t.Fatal("change expected")
+	}
+	if err := matcher.Synced().AcceptApproval("approvals", change.Approval("qa")); err != nil {
+		t.Fatal(err)
+	}
+
+	// gitdir indents json bodies
+	result, err := Migrate(src, gitdir.NewRegistry(t.TempDir()), MigrateOptions{Verify: true})
+	if err != nil || result.Copied != 6 {
+		t.Error("expected verified migration", result, err)
+	}
+}
+
+func TestMigrateListed(t *testing.T) {
+	src, dest := newMemRegistry(), newMemRegistry()
+	_ = src.Push("a/1", registry.Object{Body: []byte("1")})
+	_ = src.Push("a/2", registry.Object{Body: []byte("2")})
+	_ = src.Push("b/3", registry.Object{Body: []byte("3")})
+	progress := filepath.Join(t.TempDir(), "progress")
+
+	result, err := Migrate(src, dest, MigrateOptions{Prefix: "a/", Progress: progress})
+	if err != nil || result.Copied != 2 {
+		t.Error("expected two copied objects", result, err)
+	}
+	result, err = Migrate(src, dest, MigrateOptions{Progress: progress})
+	if err != nil || result.Copied != 1 || result.Skipped != 2 {
+		t.Error("expected resumed migration", result, err)
+	}
+}
+
+func TestMigrateBaselines(t *testing.T) {
+	src, dest := newMemRegistry(), newMemRegistry()
+	SetRegistry(src)
+	matcher := NewMatcher("run").ApprovalEnabled(false, "").KeepHistory(10)
+	_ = matcher.New("page").Compare(testImage(64, 64, 16))
+	_ = matcher.Update(true).New("page").Compare(testImage(64, 64, 48))
+
+	result, err := Migrate(unlisted{src}, dest, MigrateOptions{Baselines: []string{"page"}, Rename: func(key string) string { return "v2/" + key }})
+	// baseline, history and its revision
+	if err != nil || result.Copied != 3 {
+		t.Fatal("expected baseline with history", result, err)
+	}
+	SetRegistry(dest)
+	revisions, err := NewSyncedOps().History("v2/page")
+	if err != nil || len(revisions) != 1 || !strings.HasPrefix(revisions[0].Snapshot, "v2/page.history/") {
+		t.Fatal("expected renamed history", revisions, err)
+	}
+	if err = new(Snapshot).Pull(revisions[0].Snapshot); err != nil {
+		t.Error("expected migrated revision", err)
+	}
+}
+
+func TestMigrateEncryptedRename(t *testing.T) {
+	src, dest := newMemRegistry(), newMemRegistry()
+	_ = src.Push("page", registry.Object{Body: []byte("sealed"), Data: map[string]string{"gosnap-encryption": "aes-gcm"}})
+	_, err := Migrate(src, dest, MigrateOptions{Rename: func(key string) string { return "v2/" + key }})
+	if !errors.Is(err, ErrEncryptedRename) {
+		t.Error("expected encrypted rename refused", err)
+	}
+}
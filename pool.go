@@ -0,0 +1,78 @@
+package gosnap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+
+	"github.com/ecwid/gosnap/registry"
+)
+
+type job struct {
+	key    string
+	target image.Image
+}
+
+// CompareAll compares targets keyed by snapshot name on a pool of Concurrency workers.
+// Changes of all targets are added to the run's batch with a single write, a buffered matcher
+// keeps them in its buffer until the caller flushes it.
+// The error joins failures of the matching itself, changes are reported by the results.
+func (m Matcher) CompareAll(ctx context.Context, targets map[string]image.Image) (map[string]MatchResult, error) {
+	owned := !m.Buffered()
+	if owned {
+		m = m.BufferChanges(true)
+	}
+	// approvals are pulled once for all targets
+	if m.approvalEnabled && m.approvalKey != "" {
+		approvals := new(Approvals)
+		err := approvals.Pull(m.approvalKey)
+		if err != nil && !errors.Is(err, registry.ErrNoSuchKey) {
+			return nil, errors.Join(ErrPullApprovals, err)
+		}
+		m.approvals = approvals
+	}
+
+	var (
+		results = make(map[string]MatchResult, len(targets))
+		errs    []error
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		jobs    = make(chan job)
+	)
+	for n := 0; n < max(m.workers, 1); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				result, err := m.New(j.key).Result(j.target)
+				mu.Lock()
+				results[j.key] = result
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", j.key, err))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for key, target := range targets {
+		select {
+		case jobs <- job{key: key, target: target}:
+		case <-ctx.Done():
+			errs = append(errs, ctx.Err())
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if owned {
+		if err := m.Flush(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,60 @@
+package gosnap
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"testing"
+)
+
+func TestCompareAll(t *testing.T) {
+	SetRegistry(newMemRegistry())
+	matcher := NewMatcher("run").ApprovalEnabled(true, "approvals").Concurrency(4)
+
+	targets := map[string]image.Image{}
+	for n := 0; n < 10; n++ {
+		targets[fmt.Sprint("page", n)] = testImage(64, 64, 16)
+	}
+	if _, err := matcher.CompareAll(context.Background(), targets); err != nil {
+		t.Fatal(err)
+	}
+	targets["page0"] = testImage(64, 64, 48)
+	targets["page1"] = testImage(64, 64, 48)
+
+	results, err := matcher.CompareAll(context.Background(), targets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := 0
+	for _, result := range results {
+		if result.Status == StatusChanged {
+			changed++
+		}
+	}
+	batch := new(Batch)
+	if err = batch.Pull("run"); err != nil {
+		t.Fatal(err)
+	}
+	if changed != 2 || len(batch.Changes) != 2 {
+		t.Error("expected two changes", results, batch.Changes)
+	}
+}
+
+func TestCompareAllBuffered(t *testing.T) {
+	SetRegistry(newMemRegistry())
+	matcher := NewMatcher("run").ApprovalEnabled(false, "").BufferChanges(true)
+	_, _ = matcher.CompareAll(context.Background(), map[string]image.Image{"page": testImage(64, 64, 16)})
+	if _, err := matcher.CompareAll(context.Background(), map[string]image.Image{"page": testImage(64, 64, 48)}); err != nil {
+		t.Fatal(err)
+	}
+	batch := new(Batch)
+	if err := batch.Pull("run"); err == nil {
+		t.Fatal("expected the caller's buffer left unflushed", batch.Changes)
+	}
+	if err := matcher.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Pull("run"); err != nil || len(batch.Changes) != 1 {
+		t.Error("expected the change flushed by the caller", batch.Changes, err)
+	}
+}
@@ -1,10 +1,14 @@
 package gosnap
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"image"
 	"image/color"
+	"log/slog"
+	"time"
 
 	"github.com/ecwid/gosnap/registry"
 )
@@ -17,6 +21,19 @@ func (p Published) Error() string {
 	return fmt.Sprint("snapshot published: ", defaultRegistry.Resolve(p.Key))
 }
 
+func (p Published) Is(target error) bool {
+	return target == ErrPublished
+}
+
+// As also supports *Published targets
+func (p Published) As(target any) bool {
+	if value, ok := target.(**Published); ok {
+		*value = &p
+		return true
+	}
+	return false
+}
+
 type subImage interface {
 	SubImage(r image.Rectangle) image.Image
 }
@@ -64,14 +81,50 @@ func (q Query) makeTargetHash(target image.Image, x, y int) Hash {
 }
 
 func (q Query) Match(target image.Image) error {
+	result, err := q.match(target)
+	if err != nil {
+		return err
+	}
+	return q.resultErr(result)
+}
+
+// Result matches the target with baseline like Compare does and explains the outcome,
+// the error is only returned if the matching itself failed
+func (q Query) Result(actual image.Image) (MatchResult, error) {
+	result, err := q.match(actual)
+	if err != nil || result.Status != StatusChanged {
+		return result, err
+	}
+	start := time.Now()
+	change, err := q.uploadChange(*result.Change)
+	result.Change = &change
+	result.Timings.Upload += time.Since(start)
+	if err != nil {
+		return result, errors.Join(err, change)
+	}
+	return result, nil
+}
+
+func (q Query) resultErr(result MatchResult) error {
+	if result.Status == StatusApproved && q.matcher.reportApproved {
+		approvals := make([]string, 0, len(result.Approvals))
+		for _, approval := range result.Approvals {
+			approvals = append(approvals, approval.String())
+		}
+		slog.Info("snapshot change auto-approved", "key", result.Key, "distance", result.Distance, "approvals", approvals)
+	}
+	return result.Err()
+}
+
+func (q Query) match(target image.Image) (result MatchResult, err error) {
 	if target == nil {
-		return errors.New("no target (actual) image set")
+		return result, ErrNoTarget
 	}
 	if q.key == "" {
-		return errors.New("baseline key is required")
+		return result, ErrNoKey
 	}
 	if q.matcher.approvalEnabled && q.matcher.approvalKey == "" {
-		return errors.New("approvalEnabled but approvalKey not defined")
+		return result, ErrNoApprovalKey
 	}
 	for _, mask := range q.masks {
 		target = Masked{
@@ -82,112 +135,195 @@ func (q Query) Match(target image.Image) error {
 	}
 
 	var (
-		err         error
 		baselineKey = q.baselineKey()
 		baseline    = new(Snapshot)
+		start       = time.Now()
 	)
+	result.Key = baselineKey
 
 	// get baseline hash
 	err = baseline.Head(baselineKey)
+	result.Timings.Baseline = time.Since(start)
 
 	// force update baseline without matching and exit
 	if errors.Is(err, registry.ErrNoSuchKey) || q.matcher.forceUpdate {
+		result.Status = StatusUpdated
+		if err != nil {
+			result.Status = StatusNew
+		}
+		start = time.Now()
 		hash := MakeHash(target, q.matcher.hashSize)
-		return q.uploadBaseline(baselineKey, hash, target)
+		result.Timings.Hash = time.Since(start)
+		return q.publishBaseline(result, hash, target)
 	}
 	if err != nil {
-		return err
+		return result, err
 	}
 
 	// Comparing the baseline with target
+	start = time.Now()
 	x, y := baseline.GetSize()
 	targetHash := q.makeTargetHash(target, x, y)
+	result.Timings.Hash = time.Since(start)
 
 	xorHash, equal := baseline.Hash.equal(targetHash, q.matcher.distance)
+	result.Distance = xorHash.onesCount()
 	if equal {
-		return nil
+		result.Status = StatusMatched
+		return result, nil
 	}
 	// update baseline and exit
 	if q.matcher.update {
-		return q.uploadBaseline(baselineKey, targetHash, target)
-	}
-	// check if approved
-	if q.matcher.approvalEnabled {
-		approvals := Approvals{}
-		err = approvals.Pull(q.matcher.approvalKey)
-		if err != nil && !errors.Is(err, registry.ErrNoSuchKey) {
-			return errors.Join(errors.New("can't pull approvals"), err)
-		}
-		if len(ApprovalsContains(approvals.Value, xorHash, q.matcher.distance)) > 0 {
-			return nil
-		}
+		result.Status = StatusUpdated
+		return q.publishBaseline(result, targetHash, target)
 	}
 
-	return Change{
+	change := Change{
 		Key:        baselineKey,
 		XorHash:    xorHash,
 		TargetHash: targetHash,
 		Data:       q.data,
 		target:     target,
 	}
-}
+	result.Status = StatusChanged
+	result.Change = &change
 
-func (q Query) UploadChange(value error) error {
-	var err error
-
-	if change, ok := value.(Change); ok {
-		// upload target image
-		change.Target, err = q.uploadSnapshot(change.TargetHash, change.target)
+	// check if approved
+	if q.matcher.approvalEnabled {
+		start = time.Now()
+		approvals, err := q.pullApprovals()
+		result.Timings.Approvals = time.Since(start)
 		if err != nil {
-			return errors.Join(err, change)
+			return result, err
 		}
+		scoped := approvals.valid(q.matcher.approvalTTL).Effective(q.matcher.quorum).scoped(baselineKey, targetHash, q.matcher.distance, q.matcher.strictApprovals)
+		if matched := ApprovalsContains(scoped, xorHash, q.matcher.distance); len(matched) > 0 {
+			change.Approvals = matched
+			result.Status = StatusApproved
+			result.Approvals = matched
+		}
+	}
 
-		// no hash matches so we need download the baseline image to make diff between them
-		baseline := new(Snapshot)
-		err = baseline.Pull(change.Key)
+	return result, nil
+}
+
+func (q Query) pullApprovals() (Approvals, error) {
+	if q.matcher.approvals != nil {
+		return *q.matcher.approvals, nil
+	}
+	approvals := Approvals{}
+	err := approvals.Pull(q.matcher.approvalKey)
+	if err != nil && !errors.Is(err, registry.ErrNoSuchKey) {
+		return approvals, errors.Join(ErrPullApprovals, err)
+	}
+	return approvals, nil
+}
+
+func (q Query) publishBaseline(result MatchResult, hash Hash, target image.Image) (MatchResult, error) {
+	start := time.Now()
+	if result.Status == StatusUpdated {
+		err := q.matcher.sync.Sync(func() error {
+			return keepRevision(result.Key, q.matcher.sync.revisions)
+		})
 		if err != nil {
-			return errors.Join(err, change)
+			return result, err
 		}
+	}
+	err := q.uploadBaseline(result.Key, hash, target)
+	result.Timings.Upload = time.Since(start)
+	if errors.As(err, new(Published)) {
+		return result, nil
+	}
+	return result, err
+}
 
-		// upload diff overlay image
-		change.Overlay, err = q.uploadSnapshot(change.XorHash, overlay(baseline.Value, change.target))
+// UploadChange uploads the change found in value, other errors joined with it are kept
+func (q Query) UploadChange(value error) error {
+	var change Change
+	if errors.As(value, &change) && !change.Approved() {
+		change, err := q.uploadChange(change)
 		if err != nil {
-			return errors.Join(err, change)
+			return errors.Join(err, replaceChange(value, change))
 		}
-
-		//
-		return q.matcher.addChangeForApproval(change)
+		return replaceChange(value, change)
 	}
-
 	return value
 }
 
-func ApprovalsContains(approvals []Approval, hash Hash, distance int) []Approval {
-	for _, tar := range approvals {
-		if hash.Equal(tar.Hash, distance) {
-			return []Approval{tar}
-		}
-	}
-	/**/
-	for _, tar1 := range approvals {
-		for _, tar2 := range approvals {
-			if hash.Equal(tar1.Hash.Or(tar2.Hash), distance) {
-				return []Approval{tar1, tar2}
+// replaceChange puts the uploaded change in place of the one found in err
+func replaceChange(err error, change Change) error {
+	switch value := err.(type) {
+	case Change:
+		return change
+	case interface{ Unwrap() []error }:
+		var errs []error
+		for _, item := range value.Unwrap() {
+			if errors.As(item, new(Change)) {
+				item = replaceChange(item, change)
 			}
+			errs = append(errs, item)
 		}
+		return errors.Join(errs...)
+	}
+	// it can't be unwrapped and rebuilt
+	return errors.Join(err, change)
+}
+
+func (q Query) uploadChange(change Change) (Change, error) {
+	var err error
+
+	// upload target image
+	change.Target, err = q.uploadSnapshot(change.TargetHash, change.target)
+	if err != nil {
+		return change, err
+	}
+
+	// no hash matches so we need download the baseline image to make diff between them
+	baseline := new(Snapshot)
+	err = baseline.Pull(change.Key)
+	if err != nil {
+		return change, err
 	}
-	return []Approval{}
+
+	// upload diff overlay image
+	change.Overlay, err = q.uploadSnapshot(change.XorHash, overlay(baseline.Value, change.target))
+	if err != nil {
+		return change, err
+	}
+
+	//
+	return q.matcher.addChangeForApproval(change)
 }
 
 func (q Query) uploadSnapshot(hash Hash, image image.Image) (key string, err error) {
+	if q.matcher.contentAddressed {
+		return q.uploadContent(hash, image)
+	}
 	key = q.matcher.generateKey()
 	err = q.pushSnapshot(key, hash, image)
 	return key, err
 }
 
+// uploadContent stores the snapshot under a digest of its png, existing one isn't uploaded again
+func (q Query) uploadContent(hash Hash, image image.Image) (string, error) {
+	obj, err := q.snapshot(hash, image).encode()
+	if err != nil {
+		return "", errors.Join(ErrUploadSnapshot, err)
+	}
+	sum := sha256.Sum256(obj.Body)
+	key := q.matcher.prependPathString() + hex.EncodeToString(sum[:])
+	if _, err = defaultRegistry.Head(key); err == nil {
+		return key, nil
+	}
+	if err = defaultRegistry.Push(key, *obj); err != nil {
+		return key, errors.Join(ErrUploadSnapshot, err)
+	}
+	return key, nil
+}
+
 func (q Query) uploadBaseline(key string, newHash Hash, newImage image.Image) error {
 	if key == "" {
-		return errors.New("can't update baseline snapshot due key is empty")
+		return ErrNoKey
 	}
 	err := q.pushSnapshot(key, newHash, newImage)
 	if err != nil {
@@ -196,22 +332,30 @@ func (q Query) uploadBaseline(key string, newHash Hash, newImage image.Image) er
 	return Published{Key: key}
 }
 
-func (q Query) pushSnapshot(key string, hash Hash, image image.Image) (err error) {
-	upload := Snapshot{
+func (q Query) snapshot(hash Hash, image image.Image) Snapshot {
+	value := Snapshot{
 		Hash:     hash,
 		Value:    image,
 		Metadata: map[string]string{},
 	}
 	for k, v := range q.data {
-		upload.Metadata[k] = v
+		value.Metadata[k] = v
 	}
-	if err = upload.Push(key); err != nil {
-		err = errors.Join(errors.New("can't upload snapshot image"), err)
+	return value
+}
+
+func (q Query) pushSnapshot(key string, hash Hash, image image.Image) (err error) {
+	if err = q.snapshot(hash, image).Push(key); err != nil {
+		err = errors.Join(ErrUploadSnapshot, err)
 	}
 	return err
 }
 
 // Compare match with baseline and upload target, overlay and approval report
 func (q Query) Compare(actual image.Image) error {
-	return q.UploadChange(q.Match(actual))
+	result, err := q.Result(actual)
+	if err != nil {
+		return err
+	}
+	return q.resultErr(result)
 }
@@ -0,0 +1,76 @@
+package gosnap
+
+// Signoff is a single reviewer's approval of a hash
+type Signoff struct {
+	Ts       int64  `json:"ts"`
+	Approver string `json:"approver"`
+	Role     string `json:"role,omitempty"`
+}
+
+// Quorum defines how many distinct approvers and which roles have to sign off an approval
+// before it becomes effective, the zero value accepts any single approver
+type Quorum struct {
+	Approvers int
+	Roles     []string
+}
+
+func (q Quorum) Satisfied(approval Approval) bool {
+	var (
+		approvers = map[string]bool{}
+		roles     = map[string]bool{}
+	)
+	for _, signoff := range approval.signoffs() {
+		approvers[signoff.Approver] = true
+		roles[signoff.Role] = true
+	}
+	if len(approvers) < q.Approvers {
+		return false
+	}
+	for _, role := range q.Roles {
+		if !roles[role] {
+			return false
+		}
+	}
+	return true
+}
+
+// signoffs of legacy approvals consist of their approver only
+func (t Approval) signoffs() []Signoff {
+	if len(t.Signoffs) == 0 {
+		return []Signoff{{Ts: t.Ts, Approver: t.Approver, Role: t.Role}}
+	}
+	return t.Signoffs
+}
+
+// signedBy returns the approval's signoffs with the patch's approver signoff added or replaced
+func (t Approval) signedBy(patch Approval) []Signoff {
+	var value = []Signoff{}
+	for _, signoff := range t.signoffs() {
+		if signoff.Approver != patch.Approver {
+			value = append(value, signoff)
+		}
+	}
+	return append(value, Signoff{Ts: patch.Ts, Approver: patch.Approver, Role: patch.Role})
+}
+
+// Effective returns approvals satisfying the quorum
+func (b Approvals) Effective(q Quorum) Approvals {
+	var value = []Approval{}
+	for _, approval := range b.Value {
+		if q.Satisfied(approval) {
+			value = append(value, approval)
+		}
+	}
+	return Approvals{Value: value}
+}
+
+// Pending returns approvals waiting for more signoffs to satisfy the quorum
+func (b Approvals) Pending(q Quorum) []Approval {
+	var value = []Approval{}
+	for _, approval := range b.Value {
+		if !q.Satisfied(approval) {
+			value = append(value, approval)
+		}
+	}
+	return value
+}
@@ -0,0 +1,25 @@
+package gosnap
+
+import "testing"
+
+func TestQuorum(t *testing.T) {
+	quorum := Quorum{Approvers: 2, Roles: []string{"design", "qa"}}
+	approvals := &Approvals{}
+
+	approvals.accept(Approval{Hash: hashString("1"), Approver: "u1", Role: "design"}, MaxApprovals, 0)
+	approvals.accept(Approval{Hash: hashString("1"), Approver: "u1", Role: "design"}, MaxApprovals, 0)
+	if len(approvals.Pending(quorum)) != 1 {
+		t.Error("single approver must not satisfy quorum", approvals.Value)
+	}
+
+	approvals.accept(Approval{Hash: hashString("1"), Approver: "u2", Role: "qa"}, MaxApprovals, 0)
+	if len(approvals.Value) != 1 || len(approvals.Value[0].Signoffs) != 2 {
+		t.Fatal("expected one approval signed off twice", approvals.Value)
+	}
+	if len(approvals.Effective(quorum).Value) != 1 {
+		t.Error("design and qa signoffs must satisfy quorum", approvals.Value)
+	}
+	if !(Quorum{}).Satisfied(Approval{Hash: hashString("2"), Approver: "legacy"}) {
+		t.Error("zero quorum must accept legacy approvals")
+	}
+}
@@ -0,0 +1,150 @@
+// Package cache keeps objects of another registry on the local disk
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ecwid/gosnap/registry"
+	"github.com/ecwid/gosnap/registry/internal/fsutil"
+)
+
+type entry struct {
+	Ts     int64             `json:"ts"`
+	Data   map[string]string `json:"data"`
+	Digest string            `json:"digest,omitempty"`
+}
+
+type cacheRegistry struct {
+	next      registry.Abstract
+	dir       string
+	ttl       time.Duration
+	immutable func(key string) bool
+}
+
+// NewRegistry caches objects of next in dir. Objects under uuid or digest keys (snapshots) never change
+// and are cached forever, other keys (baselines, approvals, batches) are cached for ttl.
+// The blobs are content-addressed so equal bodies are stored once.
+func NewRegistry(next registry.Abstract, dir string, ttl time.Duration) registry.Abstract {
+	return cacheRegistry{
+		next:      next,
+		dir:       dir,
+		ttl:       ttl,
+		immutable: registry.IsImmutableKey,
+	}
+}
+
+func digest(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c cacheRegistry) entryPath(key string) string {
+	return filepath.Join(c.dir, "keys", digest([]byte(key))+".json")
+}
+
+func (c cacheRegistry) blobPath(digest string) string {
+	return filepath.Join(c.dir, "blobs", digest)
+}
+
+// read returns the entry even if it's expired
+func (c cacheRegistry) read(key string) (*entry, bool) {
+	body, err := os.ReadFile(c.entryPath(key))
+	if err != nil {
+		return nil, false
+	}
+	var value = new(entry)
+	if err = json.Unmarshal(body, value); err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+func (c cacheRegistry) load(key string) (*entry, bool) {
+	value, ok := c.read(key)
+	if !ok || !c.immutable(key) && time.Since(time.Unix(value.Ts, 0)) > c.ttl {
+		return nil, false
+	}
+	return value, true
+}
+
+func (c cacheRegistry) store(key string, data map[string]string, body []byte) {
+	value := entry{Data: data, Digest: digest(body)}
+	if _, err := os.Stat(c.blobPath(value.Digest)); err != nil {
+		if err = fsutil.WriteFile(c.blobPath(value.Digest), bytes.NewReader(body)); err != nil {
+			return
+		}
+	}
+	c.storeEntry(key, value)
+}
+
+func (c cacheRegistry) storeEntry(key string, value entry) {
+	value.Ts = time.Now().Unix()
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	_ = fsutil.WriteFile(c.entryPath(key), bytes.NewReader(encoded))
+}
+
+func (c cacheRegistry) Head(key string) (map[string]string, error) {
+	if value, ok := c.load(key); ok {
+		return value.Data, nil
+	}
+	data, err := c.next.Head(key)
+	if err != nil {
+		return nil, err
+	}
+	// an unchanged object keeps its cached body, otherwise the entry has no body and Pull won't use it
+	value := entry{Data: data}
+	if cached, ok := c.read(key); ok && maps.Equal(cached.Data, data) {
+		value.Digest = cached.Digest
+	}
+	c.storeEntry(key, value)
+	return data, nil
+}
+
+func (c cacheRegistry) Pull(key string) (*registry.Object, error) {
+	if value, ok := c.load(key); ok && value.Digest != "" {
+		body, err := os.ReadFile(c.blobPath(value.Digest))
+		if err == nil && digest(body) == value.Digest {
+			return &registry.Object{Body: body, Data: value.Data}, nil
+		}
+	}
+	object, err := c.next.Pull(key)
+	if err != nil {
+		return nil, err
+	}
+	c.store(key, object.Data, object.Body)
+	return object, nil
+}
+
+func (c cacheRegistry) Push(key string, value registry.Object) error {
+	if err := c.next.Push(key, value); err != nil {
+		return err
+	}
+	c.store(key, value.Data, value.Body)
+	return nil
+}
+
+func (c cacheRegistry) Resolve(key string) string {
+	return c.next.Resolve(key)
+}
+
+// Unwrap lets registry.AsLister list the wrapped registry, bodies aren't streamed as the cache keeps them whole
+func (c cacheRegistry) Unwrap() registry.Abstract {
+	return c.next
+}
+
+// Middleware caches objects of the wrapped registry, see NewRegistry
+func Middleware(dir string, ttl time.Duration) registry.Middleware {
+	return func(next registry.Abstract) registry.Abstract {
+		return NewRegistry(next, dir, ttl)
+	}
+}
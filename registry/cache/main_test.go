@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ecwid/gosnap/registry"
+	"github.com/ecwid/gosnap/registry/registrytest"
+	"github.com/google/uuid"
+)
+
+func TestCache(t *testing.T) {
+	var (
+		next     = registrytest.New()
+		snapshot = "2023/" + uuid.NewString()
+		dir      = t.TempDir()
+		pulls    = func() int { return next.Calls("head") + next.Calls("pull") }
+	)
+	next.Put(snapshot, registry.Object{Body: []byte("png"), Data: map[string]string{"Hash": "1"}})
+	next.Put("approvals", registry.Object{Body: []byte("[]"), Data: map[string]string{}})
+
+	cache := NewRegistry(next, dir, 0)
+	for n := 0; n < 3; n++ {
+		if obj, err := cache.Pull(snapshot); err != nil || string(obj.Body) != "png" {
+			t.Fatal("unexpected snapshot", obj, err)
+		}
+		if _, err := cache.Pull("approvals"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if pulls() != 4 {
+		t.Error("expected snapshot to be pulled once and expired approvals every time", pulls())
+	}
+
+	// another process sharing the directory
+	before := pulls()
+	cache = NewRegistry(next, dir, time.Hour)
+	_ = cache.Push("approvals", registry.Object{Body: []byte(`[{}]`)})
+	if obj, err := cache.Pull("approvals"); err != nil || string(obj.Body) != `[{}]` || pulls() != before {
+		t.Error("expected pushed approvals from cache", obj, err, pulls())
+	}
+	if _, err := cache.Pull(snapshot); err != nil || pulls() != before {
+		t.Error("expected snapshot from cache", err, pulls())
+	}
+}
+
+// expire makes the entry of the key older than ttl
+func expire(t *testing.T, c cacheRegistry, key string) {
+	value, ok := c.read(key)
+	if !ok {
+		t.Fatal("expected cached entry", key)
+	}
+	value.Ts -= int64(c.ttl/time.Second) + 1
+	encoded, _ := json.Marshal(value)
+	if err := os.WriteFile(c.entryPath(key), encoded, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHeadKeepsBody(t *testing.T) {
+	next := registrytest.New()
+	next.Put("page", registry.Object{Body: []byte("first"), Data: map[string]string{"Hash": "1"}})
+	cache := NewRegistry(next, t.TempDir(), time.Hour).(cacheRegistry)
+
+	_, _ = cache.Pull("page")
+	expire(t, cache, "page")
+	if _, err := cache.Head("page"); err != nil {
+		t.Fatal(err)
+	}
+	if obj, err := cache.Pull("page"); err != nil || string(obj.Body) != "first" || next.Calls("pull") != 1 {
+		t.Error("expected the body kept by a head of an unchanged object", obj, err, next.Calls("pull"))
+	}
+
+	next.Put("page", registry.Object{Body: []byte("second"), Data: map[string]string{"Hash": "2"}})
+	expire(t, cache, "page")
+	if _, err := cache.Head("page"); err != nil {
+		t.Fatal(err)
+	}
+	if obj, err := cache.Pull("page"); err != nil || string(obj.Body) != "second" || next.Calls("pull") != 2 {
+		t.Error("expected the changed object pulled", obj, err, next.Calls("pull"))
+	}
+}
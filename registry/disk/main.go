@@ -0,0 +1,152 @@
+// Package disk stores objects as files in a local directory
+package disk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ecwid/gosnap/registry"
+	"github.com/ecwid/gosnap/registry/internal/fsutil"
+)
+
+var ErrInvalidKey = errors.New("invalid key")
+
+type diskRegistry struct {
+	dir     string
+	baseURL string
+}
+
+// NewRegistry stores bodies under dir/data and metadata under dir/meta, both named after the key.
+// Resolve joins baseURL with the key, or returns the file path if baseURL is empty.
+func NewRegistry(dir, baseURL string) registry.Abstract {
+	return diskRegistry{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// clean rejects keys escaping the directory
+func clean(key string) (string, error) {
+	value := path.Clean("/" + key)[1:]
+	if value == "" || value != key {
+		return "", errors.Join(ErrInvalidKey, errors.New(key))
+	}
+	return filepath.FromSlash(value), nil
+}
+
+func (d diskRegistry) paths(key string) (data string, meta string, err error) {
+	name, err := clean(key)
+	if err != nil {
+		return "", "", err
+	}
+	// suffixes let a key be a prefix of another one like "page" and "page/1"
+	return filepath.Join(d.dir, "data", name+".body"), filepath.Join(d.dir, "meta", name+".json"), nil
+}
+
+func notExist(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return registry.ErrNoSuchKey
+	}
+	return err
+}
+
+func (d diskRegistry) Resolve(key string) string {
+	if d.baseURL != "" {
+		return d.baseURL + "/" + key
+	}
+	data, _, _ := d.paths(key)
+	return data
+}
+
+func (d diskRegistry) Head(key string) (map[string]string, error) {
+	_, meta, err := d.paths(key)
+	if err != nil {
+		return nil, err
+	}
+	body, err := os.ReadFile(meta)
+	if err != nil {
+		return nil, notExist(err)
+	}
+	data := map[string]string{}
+	if err = json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (d diskRegistry) PullStream(key string) (io.ReadCloser, map[string]string, error) {
+	data, err := d.Head(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	name, _, _ := d.paths(key)
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, nil, notExist(err)
+	}
+	return file, data, nil
+}
+
+func (d diskRegistry) Pull(key string) (*registry.Object, error) {
+	body, data, err := d.PullStream(key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	value, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	return &registry.Object{Body: value, Data: data}, nil
+}
+
+// PushStream writes the body before the metadata, so Head never sees a missing body
+func (d diskRegistry) PushStream(key string, body io.Reader, data map[string]string) error {
+	name, meta, err := d.paths(key)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		data = map[string]string{}
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if err = fsutil.WriteFile(name, body); err != nil {
+		return err
+	}
+	return fsutil.WriteFile(meta, bytes.NewReader(encoded))
+}
+
+func (d diskRegistry) List(prefix string) ([]string, error) {
+	var (
+		keys []string
+		root = filepath.Join(d.dir, "meta")
+	)
+	err := filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			return err
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		if key := strings.TrimSuffix(filepath.ToSlash(rel), ".json"); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return keys, err
+}
+
+func (d diskRegistry) Push(key string, value registry.Object) error {
+	return d.PushStream(key, bytes.NewReader(value.Body), value.Data)
+}
@@ -0,0 +1,41 @@
+package disk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ecwid/gosnap/registry"
+	"github.com/ecwid/gosnap/registry/registrytest"
+)
+
+func TestConformance(t *testing.T) {
+	registrytest.Conformance(t, NewRegistry(t.TempDir(), ""))
+}
+
+func TestInvalidKeys(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "store")
+	r := NewRegistry(dir, "")
+	for _, key := range []string{"../escape", "a/../../escape", "/abs", "a//b", "a/", ""} {
+		if err := r.Push(key, registry.Object{Body: []byte("x")}); !errors.Is(err, ErrInvalidKey) {
+			t.Error("expected ErrInvalidKey from Push", key, err)
+		}
+		if _, err := r.Pull(key); !errors.Is(err, ErrInvalidKey) {
+			t.Error("expected ErrInvalidKey from Pull", key, err)
+		}
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 0 {
+		t.Error("expected nothing written", entries)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	if value := NewRegistry("dir", "https://example.com/snapshots/").Resolve("a/page"); value != "https://example.com/snapshots/a/page" {
+		t.Error("unexpected url", value)
+	}
+	if value := NewRegistry("dir", "").Resolve("a/page"); value != filepath.Join("dir", "data", "a", "page.body") {
+		t.Error("unexpected path", value)
+	}
+}
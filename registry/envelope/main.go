@@ -0,0 +1,192 @@
+// Package envelope compresses and encrypts bodies of another registry's objects
+package envelope
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/ecwid/gosnap/registry"
+)
+
+// Object.Data keys recording how the body is stored
+const (
+	DataEncoding   = "Gosnap-Encoding"
+	DataEncryption = "Gosnap-Encryption"
+
+	encodingGzip  = "gzip"
+	encryptionGCM = "aes-gcm"
+)
+
+var ErrUnknownScheme = errors.New("unknown body scheme")
+
+type Options struct {
+	// Compress gzips JSON bodies like approvals and change batches
+	Compress bool
+	// Key of 16, 24 or 32 bytes enables AES-GCM encryption of all bodies
+	Key []byte
+}
+
+type envelopeRegistry struct {
+	next     registry.Abstract
+	compress bool
+	aead     cipher.AEAD
+}
+
+// NewRegistry stores bodies of next compressed and encrypted according to opts,
+// objects stored without the envelope are still read as is
+func NewRegistry(next registry.Abstract, opts Options) (registry.Abstract, error) {
+	value := envelopeRegistry{next: next, compress: opts.Compress}
+	if opts.Key != nil {
+		block, err := aes.NewCipher(opts.Key)
+		if err != nil {
+			return nil, err
+		}
+		if value.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// Middleware wraps a registry with the envelope, see NewRegistry
+func Middleware(opts Options) (registry.Middleware, error) {
+	if _, err := NewRegistry(nil, opts); err != nil {
+		return nil, err
+	}
+	return func(next registry.Abstract) registry.Abstract {
+		value, _ := NewRegistry(next, opts)
+		return value
+	}, nil
+}
+
+// lookup ignores the case since some registries canonicalize metadata keys
+func lookup(data map[string]string, key string) (string, bool) {
+	for k, v := range data {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// strip copies data without the envelope keys
+func strip(data map[string]string) map[string]string {
+	if data == nil {
+		return nil
+	}
+	value := make(map[string]string, len(data))
+	for k, v := range data {
+		if !strings.EqualFold(k, DataEncoding) && !strings.EqualFold(k, DataEncryption) {
+			value[k] = v
+		}
+	}
+	return value
+}
+
+func (e envelopeRegistry) seal(key string, body []byte, data map[string]string) ([]byte, error) {
+	if e.compress && json.Valid(body) {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		body = buf.Bytes()
+		data[DataEncoding] = encodingGzip
+	}
+	if e.aead != nil {
+		nonce := make([]byte, e.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		body = e.aead.Seal(nonce, nonce, body, []byte(key))
+		data[DataEncryption] = encryptionGCM
+	}
+	return body, nil
+}
+
+func (e envelopeRegistry) open(key string, body []byte, data map[string]string) ([]byte, error) {
+	if scheme, ok := lookup(data, DataEncryption); ok {
+		if scheme != encryptionGCM || e.aead == nil {
+			return nil, errors.Join(ErrUnknownScheme, errors.New(scheme))
+		}
+		n := e.aead.NonceSize()
+		if len(body) < n {
+			return nil, errors.New("encrypted body is too short")
+		}
+		var err error
+		if body, err = e.aead.Open(nil, body[:n], body[n:], []byte(key)); err != nil {
+			return nil, err
+		}
+	}
+	if encoding, ok := lookup(data, DataEncoding); ok {
+		if encoding != encodingGzip {
+			return nil, errors.Join(ErrUnknownScheme, errors.New(encoding))
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		if body, err = io.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+func (e envelopeRegistry) Head(key string) (map[string]string, error) {
+	data, err := e.next.Head(key)
+	if err != nil {
+		return nil, err
+	}
+	return strip(data), nil
+}
+
+func (e envelopeRegistry) Pull(key string) (*registry.Object, error) {
+	object, err := e.next.Pull(key)
+	if err != nil {
+		return nil, err
+	}
+	if object.Body != nil {
+		if object.Body, err = e.open(key, object.Body, object.Data); err != nil {
+			return nil, err
+		}
+	}
+	object.Data = strip(object.Data)
+	return object, nil
+}
+
+func (e envelopeRegistry) Push(key string, value registry.Object) error {
+	data := strip(value.Data)
+	if data == nil {
+		data = map[string]string{}
+	}
+	if value.Body != nil {
+		body, err := e.seal(key, value.Body, data)
+		if err != nil {
+			return err
+		}
+		value.Body = body
+	}
+	value.Data = data
+	return e.next.Push(key, value)
+}
+
+func (e envelopeRegistry) Resolve(key string) string {
+	return e.next.Resolve(key)
+}
+
+// Unwrap lets registry.AsLister list the wrapped registry, bodies aren't streamed as they're sealed whole
+func (e envelopeRegistry) Unwrap() registry.Abstract {
+	return e.next
+}
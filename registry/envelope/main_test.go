@@ -0,0 +1,46 @@
+package envelope
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ecwid/gosnap/registry"
+	"github.com/ecwid/gosnap/registry/registrytest"
+)
+
+func TestEnvelope(t *testing.T) {
+	next := registrytest.New()
+	r, err := NewRegistry(next, Options{Compress: true, Key: bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	approvals := []byte(`[{"hash":"1","approver":"u1"},{"hash":"2","approver":"u1"}]`)
+	png := []byte("\x89PNG not json")
+	_ = r.Push("approvals", registry.Object{Body: approvals, Data: map[string]string{"Hash": "1"}})
+	_ = r.Push("snapshot", registry.Object{Body: png})
+
+	stored, _ := next.Object("approvals")
+	if stored.Data[DataEncoding] != "gzip" || stored.Data[DataEncryption] != "aes-gcm" {
+		t.Error("expected compressed and encrypted approvals", stored.Data)
+	}
+	stored, _ = next.Object("snapshot")
+	if _, ok := stored.Data[DataEncoding]; ok || bytes.Contains(stored.Body, png) {
+		t.Error("expected encrypted uncompressed snapshot", stored)
+	}
+
+	for key, body := range map[string][]byte{"approvals": approvals, "snapshot": png} {
+		obj, err := r.Pull(key)
+		if err != nil || !bytes.Equal(obj.Body, body) {
+			t.Error("unexpected body", key, obj, err)
+		}
+		if _, ok := obj.Data[DataEncryption]; ok {
+			t.Error("envelope data must be stripped", obj.Data)
+		}
+	}
+
+	// objects must not be readable under another key
+	next.Put("moved", stored)
+	if _, err = r.Pull("moved"); err == nil {
+		t.Error("expected authentication failure")
+	}
+}
@@ -0,0 +1,218 @@
+// Package gitdir stores objects as reviewable files in a directory of a git repository.
+//
+// Bodies are written to files named after their keys, png snapshots with the .png
+// extension and approvals or change batches as indented .json. Metadata of every
+// object is kept in its own sorted json file under .gosnap/index, so a pull request
+// shows every baseline change as a file diff and concurrent processes never
+// overwrite each other's entries.
+//
+// Target and overlay snapshots of changes (uuid or digest keys) are kept under
+// .gosnap/objects and .gosnap/objects-index instead, and change batches are stored
+// under their run id. Neither is worth a review, a .gitignore like
+//
+//	.gosnap/objects/
+//	.gosnap/objects-index/
+//	runs/
+//	.gosnap/index/runs/
+//
+// keeps them out of commits if run ids are prefixed with "runs/". Baseline histories
+// and their revision snapshots (<key>.history/<time>.png) are regular files committed
+// with baselines, so a rollback works in a fresh clone.
+package gitdir
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ecwid/gosnap/registry"
+	"github.com/ecwid/gosnap/registry/internal/fsutil"
+)
+
+const (
+	indexDir        = ".gosnap/index"
+	objectsDir      = ".gosnap/objects"
+	objectsIndexDir = ".gosnap/objects-index"
+)
+
+type entry struct {
+	Key  string            `json:"key"`
+	File string            `json:"file"`
+	Data map[string]string `json:"data,omitempty"`
+}
+
+type gitRegistry struct {
+	dir string
+}
+
+func NewRegistry(dir string) registry.Abstract {
+	return gitRegistry{dir: dir}
+}
+
+func safe(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+		r == '-' || r == '_' || r == '.' || r == '/'
+}
+
+// stem maps the key to a relative path, keys with unsafe characters
+// get a digest suffix so different keys never share a file
+func stem(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if safe(r) {
+			return r
+		}
+		return '_'
+	}, key)
+	name = path.Clean("/" + name)[1:]
+	name = strings.ReplaceAll(name, "..", "_")
+	if name != key || name == "" {
+		sum := sha256.Sum256([]byte(key))
+		name += "-" + hex.EncodeToString(sum[:4])
+	}
+	return name
+}
+
+// FileName derives the file name from the key and body
+func FileName(key string, body []byte) string {
+	name := stem(key)
+	switch {
+	case bytes.HasPrefix(body, []byte("\x89PNG")):
+		return name + ".png"
+	case json.Valid(body):
+		return name + ".json"
+	}
+	return name + ".bin"
+}
+
+// dirs returns directories of the key's body and metadata files
+func (g gitRegistry) dirs(key string) (data string, index string) {
+	if registry.IsImmutableKey(key) {
+		return filepath.Join(g.dir, objectsDir), filepath.Join(g.dir, objectsIndexDir)
+	}
+	return g.dir, filepath.Join(g.dir, indexDir)
+}
+
+func (g gitRegistry) entryPath(key string) string {
+	_, index := g.dirs(key)
+	return filepath.Join(index, filepath.FromSlash(stem(key))+".json")
+}
+
+func (g gitRegistry) filePath(key, file string) string {
+	data, _ := g.dirs(key)
+	return filepath.Join(data, filepath.FromSlash(file))
+}
+
+func readEntry(name string) (entry, error) {
+	var value entry
+	body, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return value, registry.ErrNoSuchKey
+	}
+	if err != nil {
+		return value, err
+	}
+	if err = json.Unmarshal(body, &value); err != nil {
+		return value, err
+	}
+	if value.Data == nil {
+		value.Data = map[string]string{}
+	}
+	return value, nil
+}
+
+func (g gitRegistry) lookup(key string) (entry, error) {
+	value, err := readEntry(g.entryPath(key))
+	if err == nil && value.Key != key {
+		return value, registry.ErrNoSuchKey
+	}
+	return value, err
+}
+
+func (g gitRegistry) Head(key string) (map[string]string, error) {
+	value, err := g.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	return value.Data, nil
+}
+
+func (g gitRegistry) Pull(key string) (*registry.Object, error) {
+	value, err := g.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	body, err := os.ReadFile(g.filePath(key, value.File))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, registry.ErrNoSuchKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &registry.Object{Body: body, Data: value.Data}, nil
+}
+
+func (g gitRegistry) Push(key string, object registry.Object) error {
+	body := object.Body
+	name := FileName(key, body)
+	if strings.HasSuffix(name, ".json") {
+		// indented json makes reviewable diffs
+		var buf bytes.Buffer
+		if json.Indent(&buf, body, "", "  ") == nil {
+			body = append(buf.Bytes(), '\n')
+		}
+	}
+	if err := fsutil.WriteFile(g.filePath(key, name), bytes.NewReader(body)); err != nil {
+		return err
+	}
+	if prev, err := g.lookup(key); err == nil && prev.File != name {
+		_ = os.Remove(g.filePath(key, prev.File))
+	}
+	encoded, err := json.MarshalIndent(entry{Key: key, File: name, Data: object.Data}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFile(g.entryPath(key), bytes.NewReader(append(encoded, '\n')))
+}
+
+func (g gitRegistry) List(prefix string) ([]string, error) {
+	var keys []string
+	for _, dir := range []string{indexDir, objectsIndexDir} {
+		err := filepath.WalkDir(filepath.Join(g.dir, dir), func(name string, d fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			if err != nil || d.IsDir() || !strings.HasSuffix(name, ".json") {
+				return err
+			}
+			value, err := readEntry(name)
+			if err != nil {
+				return err
+			}
+			if strings.HasPrefix(value.Key, prefix) {
+				keys = append(keys, value.Key)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Resolve returns the path of the object's file
+func (g gitRegistry) Resolve(key string) string {
+	if value, err := g.lookup(key); err == nil {
+		return g.filePath(key, value.File)
+	}
+	return g.filePath(key, FileName(key, nil))
+}
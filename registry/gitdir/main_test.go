@@ -0,0 +1,86 @@
+package gitdir
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ecwid/gosnap/registry"
+	"github.com/google/uuid"
+)
+
+func TestFileName(t *testing.T) {
+	for key, name := range map[string]string{
+		"2023/chromium/page": "2023/chromium/page.png",
+		"../page":            "page-",
+		"page?x=1":           "page_x_1-",
+	} {
+		got := FileName(key, []byte("\x89PNG"))
+		if !strings.HasPrefix(got, name) {
+			t.Error("unexpected file name", key, got)
+		}
+	}
+	if FileName("approvals", []byte("[]")) != "approvals.json" {
+		t.Error("expected json file")
+	}
+}
+
+func TestPushPull(t *testing.T) {
+	dir := t.TempDir()
+	r := NewRegistry(dir)
+	_ = r.Push("2023/page", registry.Object{Body: []byte("\x89PNG..."), Data: map[string]string{"Hash": "1"}})
+	_ = r.Push("approvals", registry.Object{Body: []byte(`[{"hash":"1"}]`)})
+
+	if _, err := os.Stat(filepath.Join(dir, "2023", "page.png")); err != nil {
+		t.Error("expected png file", err)
+	}
+	obj, err := r.Pull("approvals")
+	if err != nil || string(obj.Body) != "[\n  {\n    \"hash\": \"1\"\n  }\n]\n" {
+		t.Error("expected indented approvals", obj, err)
+	}
+	if data, err := r.Head("2023/page"); err != nil || data["Hash"] != "1" {
+		t.Error("expected metadata from the key's index entry", data, err)
+	}
+}
+
+func TestConcurrentPush(t *testing.T) {
+	dir := t.TempDir()
+	if keys, err := NewRegistry(dir).(registry.Lister).List(""); err != nil || len(keys) != 0 {
+		t.Fatal("expected empty registry", keys, err)
+	}
+	var wg sync.WaitGroup
+	for n := 0; n < 20; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			// a registry per goroutine like separate test processes
+			_ = NewRegistry(dir).Push(fmt.Sprint("page", n), registry.Object{Body: []byte("\x89PNG")})
+		}(n)
+	}
+	wg.Wait()
+	keys, err := NewRegistry(dir).(registry.Lister).List("page")
+	if err != nil || len(keys) != 20 {
+		t.Error("expected every key listed", keys, err)
+	}
+}
+
+func TestObjectsKeptApart(t *testing.T) {
+	dir := t.TempDir()
+	r := NewRegistry(dir)
+	key := "2023/" + uuid.NewString()
+	if err := r.Push(key, registry.Object{Body: []byte("\x89PNG")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, objectsDir, key+".png")); err != nil {
+		t.Error("expected snapshot under objects", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2023")); err == nil {
+		t.Error("unexpected snapshot in the reviewed tree")
+	}
+	if obj, err := r.Pull(key); err != nil || string(obj.Body) != "\x89PNG" {
+		t.Error("unexpected object", obj, err)
+	}
+}
@@ -0,0 +1,78 @@
+package httpreg
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ecwid/gosnap/registry"
+)
+
+type handler struct {
+	store registry.Abstract
+	token string
+}
+
+// NewHandler serves objects of the store to the HTTP registry, token is required if set
+func NewHandler(store registry.Abstract, token string) http.Handler {
+	return handler{store: store, token: token}
+}
+
+func writeErr(w http.ResponseWriter, err error) {
+	if errors.Is(err, registry.ErrNoSuchKey) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+h.token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	if key == "" {
+		http.Error(w, "key is required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodHead:
+		data, err := h.store.Head(key)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		w.Header().Set(HeaderData, encodeData(data))
+	case http.MethodGet:
+		body, data, err := registry.PullStream(h.store, key)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		defer body.Close()
+		reader := bufio.NewReader(body)
+		head, _ := reader.Peek(512)
+		w.Header().Set(HeaderData, encodeData(data))
+		w.Header().Set("Content-Type", http.DetectContentType(head))
+		_, _ = io.Copy(w, reader)
+	case http.MethodPut:
+		data, err := decodeData(r.Header.Get(HeaderData))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = registry.PushStream(h.store, key, r.Body, data); err != nil {
+			writeErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,151 @@
+// Package httpreg is a registry talking to a snapshot store over HTTP.
+//
+// Objects are read with GET and HEAD and written with PUT of {base}/{key},
+// metadata travels url encoded in the X-Gosnap-Data header.
+package httpreg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ecwid/gosnap/registry"
+)
+
+const HeaderData = "X-Gosnap-Data"
+
+type Options struct {
+	// Client is http.DefaultClient by default
+	Client *http.Client
+	// Token is sent as a bearer authorization if set
+	Token string
+}
+
+type httpRegistry struct {
+	base string
+	opts Options
+}
+
+func NewRegistry(base string, opts Options) registry.Abstract {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	return httpRegistry{base: strings.TrimSuffix(base, "/"), opts: opts}
+}
+
+func encodeData(data map[string]string) string {
+	values := url.Values{}
+	for k, v := range data {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+func decodeData(header string) (map[string]string, error) {
+	values, err := url.ParseQuery(header)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]string, len(values))
+	for k := range values {
+		data[k] = values.Get(k)
+	}
+	return data, nil
+}
+
+func statusErr(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return registry.ErrNoSuchKey
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return errors.Join(registry.ErrTransient, fmt.Errorf("http status %s", resp.Status))
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("http status %s", resp.Status)
+	}
+	return nil
+}
+
+func (h httpRegistry) do(method, key string, body io.Reader, data map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(method, h.Resolve(key), body)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		req.Header.Set(HeaderData, encodeData(data))
+	}
+	if h.opts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.opts.Token)
+	}
+	resp, err := h.opts.Client.Do(req)
+	if err != nil {
+		return nil, errors.Join(registry.ErrTransient, err)
+	}
+	if err = statusErr(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Resolve escapes every segment of the key so "#", "?" and "%" stay part of it
+func (h httpRegistry) Resolve(key string) string {
+	segments := strings.Split(key, "/")
+	for n, segment := range segments {
+		segments[n] = url.PathEscape(segment)
+	}
+	return h.base + "/" + strings.Join(segments, "/")
+}
+
+func (h httpRegistry) Head(key string) (map[string]string, error) {
+	resp, err := h.do(http.MethodHead, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return decodeData(resp.Header.Get(HeaderData))
+}
+
+func (h httpRegistry) PullStream(key string) (io.ReadCloser, map[string]string, error) {
+	resp, err := h.do(http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := decodeData(resp.Header.Get(HeaderData))
+	if err != nil {
+		resp.Body.Close()
+		return nil, nil, err
+	}
+	return resp.Body, data, nil
+}
+
+func (h httpRegistry) Pull(key string) (*registry.Object, error) {
+	body, data, err := h.PullStream(key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	value, err := io.ReadAll(body)
+	if err != nil {
+		return nil, errors.Join(registry.ErrTransient, err)
+	}
+	return &registry.Object{Body: value, Data: data}, nil
+}
+
+func (h httpRegistry) PushStream(key string, body io.Reader, data map[string]string) error {
+	if data == nil {
+		data = map[string]string{}
+	}
+	resp, err := h.do(http.MethodPut, key, body, data)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (h httpRegistry) Push(key string, value registry.Object) error {
+	return h.PushStream(key, bytes.NewReader(value.Body), value.Data)
+}
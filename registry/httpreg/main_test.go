@@ -0,0 +1,56 @@
+package httpreg
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ecwid/gosnap/registry"
+	"github.com/ecwid/gosnap/registry/disk"
+)
+
+func TestRoundTrip(t *testing.T) {
+	server := httptest.NewServer(NewHandler(disk.NewRegistry(t.TempDir(), ""), "secret"))
+	defer server.Close()
+	r := NewRegistry(server.URL, Options{Token: "secret"})
+
+	if _, err := r.Head("2023/page"); !errors.Is(err, registry.ErrNoSuchKey) {
+		t.Error("expected no such key", err)
+	}
+	data := map[string]string{"Hash": "abc", "user": "me & you"}
+	if err := r.Push("2023/page", registry.Object{Body: []byte("body"), Data: data}); err != nil {
+		t.Fatal(err)
+	}
+	obj, err := r.Pull("2023/page")
+	if err != nil || string(obj.Body) != "body" || obj.Data["user"] != "me & you" || obj.Data["Hash"] != "abc" {
+		t.Error("unexpected object", obj, err)
+	}
+	if _, err = r.Pull("../escape"); err == nil {
+		t.Error("expected invalid key")
+	}
+	if _, err = NewRegistry(server.URL, Options{}).Head("2023/page"); err == nil {
+		t.Error("expected unauthorized")
+	}
+}
+
+func TestEscapedKeys(t *testing.T) {
+	server := httptest.NewServer(NewHandler(disk.NewRegistry(t.TempDir(), ""), ""))
+	defer server.Close()
+	r := NewRegistry(server.URL, Options{})
+
+	keys := []string{"TestX/sub", "TestX/sub#01", "TestX/sub?x=1", "page 100%", "a b/c%2Fd"}
+	for _, key := range keys {
+		if err := r.Push(key, registry.Object{Body: []byte(key)}); err != nil {
+			t.Fatal(key, err)
+		}
+	}
+	for _, key := range keys {
+		obj, err := r.Pull(key)
+		if err != nil || string(obj.Body) != key {
+			t.Error("unexpected object", key, obj, err)
+		}
+		if _, err = r.Head(key); err != nil {
+			t.Error(key, err)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+
+	"github.com/google/uuid"
+)
+
+// IsUUIDKey reports whether the last segment of the key is an uuid
+func IsUUIDKey(key string) bool {
+	_, err := uuid.Parse(path.Base(key))
+	return err == nil
+}
+
+// IsDigestKey reports whether the last segment of the key is a hex sha256 of a content addressed snapshot
+func IsDigestKey(key string) bool {
+	base := path.Base(key)
+	if len(base) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(base)
+	return err == nil
+}
+
+// IsImmutableKey reports whether the key is of a target or overlay snapshot that never changes
+func IsImmutableKey(key string) bool {
+	return IsUUIDKey(key) || IsDigestKey(key)
+}
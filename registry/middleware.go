@@ -0,0 +1,119 @@
+package registry
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// Middleware wraps a registry to add behaviour around its operations
+type Middleware func(Abstract) Abstract
+
+// Wrap applies middlewares to the registry, the first one is the outermost
+func Wrap(registry Abstract, middlewares ...Middleware) Abstract {
+	for n := len(middlewares) - 1; n >= 0; n-- {
+		registry = middlewares[n](registry)
+	}
+	return registry
+}
+
+type Op string
+
+const (
+	OpHead Op = "head"
+	OpPull Op = "pull"
+	OpPush Op = "push"
+)
+
+// Hook is called before an operation and returns a function called with its outcome,
+// size is the body length of pulled or pushed objects
+type Hook func(op Op, key string) func(size int, duration time.Duration, err error)
+
+// Observe calls hook around every Head, Pull and Push
+func Observe(hook Hook) Middleware {
+	return func(next Abstract) Abstract {
+		return observed{next: next, hook: hook}
+	}
+}
+
+type observed struct {
+	next Abstract
+	hook Hook
+}
+
+func (o observed) Head(key string) (map[string]string, error) {
+	done, start := o.hook(OpHead, key), time.Now()
+	data, err := o.next.Head(key)
+	done(0, time.Since(start), err)
+	return data, err
+}
+
+func (o observed) Pull(key string) (*Object, error) {
+	done, start := o.hook(OpPull, key), time.Now()
+	object, err := o.next.Pull(key)
+	size := 0
+	if object != nil {
+		size = len(object.Body)
+	}
+	done(size, time.Since(start), err)
+	return object, err
+}
+
+func (o observed) Push(key string, value Object) error {
+	done, start := o.hook(OpPush, key), time.Now()
+	err := o.next.Push(key, value)
+	done(len(value.Body), time.Since(start), err)
+	return err
+}
+
+func (o observed) Resolve(key string) string {
+	return o.next.Resolve(key)
+}
+
+func (o observed) Unwrap() Abstract {
+	return o.next
+}
+
+// PullStream reports the outcome once the body is closed
+func (o observed) PullStream(key string) (io.ReadCloser, map[string]string, error) {
+	done, start := o.hook(OpPull, key), time.Now()
+	body, data, err := PullStream(o.next, key)
+	if err != nil {
+		done(0, time.Since(start), err)
+		return nil, nil, err
+	}
+	return &observedBody{ReadCloser: body, done: func(size int, err error) { done(size, time.Since(start), err) }}, data, nil
+}
+
+func (o observed) PushStream(key string, body io.Reader, data map[string]string) error {
+	done, start := o.hook(OpPush, key), time.Now()
+	counted := &observedBody{ReadCloser: io.NopCloser(body)}
+	err := PushStream(o.next, key, counted, data)
+	done(counted.size, time.Since(start), err)
+	return err
+}
+
+type observedBody struct {
+	io.ReadCloser
+	size int
+	err  error
+	done func(size int, err error)
+}
+
+func (b *observedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += n
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *observedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.done != nil {
+		b.done(b.size, errors.Join(b.err, err))
+		b.done = nil
+	}
+	return err
+}
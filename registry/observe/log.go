@@ -0,0 +1,27 @@
+// Package observe provides logging, metrics and tracing middlewares for registries
+package observe
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/ecwid/gosnap/registry"
+)
+
+// Logging logs registry operations, failures other than registry.ErrNoSuchKey are logged as errors
+func Logging(logger *slog.Logger) registry.Middleware {
+	return registry.Observe(func(op registry.Op, key string) func(int, time.Duration, error) {
+		return func(size int, duration time.Duration, err error) {
+			attrs := []any{"op", op, "key", key, "size", size, "duration", duration}
+			switch {
+			case err == nil:
+				logger.Debug("gosnap registry", attrs...)
+			case errors.Is(err, registry.ErrNoSuchKey):
+				logger.Debug("gosnap registry: no such key", attrs...)
+			default:
+				logger.Error("gosnap registry", append(attrs, "error", err)...)
+			}
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package observe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ecwid/gosnap/registry"
+	"github.com/ecwid/gosnap/registry/retry"
+)
+
+// DefaultBuckets of the request duration histogram in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type requestLabels struct {
+	op     registry.Op
+	result string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Collector keeps Prometheus style counters and histograms of registry operations
+type Collector struct {
+	// Retry counters are exported as well if set
+	Retry *retry.Metrics
+
+	mu        sync.Mutex
+	buckets   []float64
+	requests  map[requestLabels]uint64
+	bytes     map[registry.Op]uint64
+	durations map[registry.Op]*histogram
+}
+
+func NewCollector(buckets ...float64) *Collector {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &Collector{
+		buckets:   buckets,
+		requests:  map[requestLabels]uint64{},
+		bytes:     map[registry.Op]uint64{},
+		durations: map[registry.Op]*histogram{},
+	}
+}
+
+func result(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, registry.ErrNoSuchKey):
+		return "no_such_key"
+	}
+	return "error"
+}
+
+func (c *Collector) observe(op registry.Op, size int, duration time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests[requestLabels{op: op, result: result(err)}]++
+	c.bytes[op] += uint64(size)
+	h, ok := c.durations[op]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(c.buckets))}
+		c.durations[op] = h
+	}
+	seconds := duration.Seconds()
+	for n, le := range c.buckets {
+		if seconds <= le {
+			h.counts[n]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// Metrics counts registry operations into the collector
+func Metrics(c *Collector) registry.Middleware {
+	return registry.Observe(func(op registry.Op, _ string) func(int, time.Duration, error) {
+		return func(size int, duration time.Duration, err error) {
+			c.observe(op, size, duration, err)
+		}
+	})
+}
+
+// WritePrometheus writes metrics in the Prometheus text exposition format
+func (c *Collector) WritePrometheus(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var lines []string
+	printf := func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+
+	printf("# TYPE gosnap_registry_requests_total counter")
+	labels := make([]requestLabels, 0, len(c.requests))
+	for l := range c.requests {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].op != labels[j].op {
+			return labels[i].op < labels[j].op
+		}
+		return labels[i].result < labels[j].result
+	})
+	for _, l := range labels {
+		printf(`gosnap_registry_requests_total{op="%s",result="%s"} %d`, l.op, l.result, c.requests[l])
+	}
+
+	ops := make([]registry.Op, 0, len(c.durations))
+	for op := range c.durations {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i] < ops[j] })
+
+	printf("# TYPE gosnap_registry_bytes_total counter")
+	for _, op := range ops {
+		printf(`gosnap_registry_bytes_total{op="%s"} %d`, op, c.bytes[op])
+	}
+
+	printf("# TYPE gosnap_registry_request_duration_seconds histogram")
+	for _, op := range ops {
+		h := c.durations[op]
+		for n, le := range c.buckets {
+			printf(`gosnap_registry_request_duration_seconds_bucket{op="%s",le="%g"} %d`, op, le, h.counts[n])
+		}
+		printf(`gosnap_registry_request_duration_seconds_bucket{op="%s",le="+Inf"} %d`, op, h.count)
+		printf(`gosnap_registry_request_duration_seconds_sum{op="%s"} %g`, op, h.sum)
+		printf(`gosnap_registry_request_duration_seconds_count{op="%s"} %d`, op, h.count)
+	}
+
+	if c.Retry != nil {
+		printf("# TYPE gosnap_registry_retries_total counter")
+		printf("gosnap_registry_retries_total %d", c.Retry.Retries.Load())
+		printf("# TYPE gosnap_registry_retries_exhausted_total counter")
+		printf("gosnap_registry_retries_exhausted_total %d", c.Retry.Exhausted.Load())
+	}
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Collector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_ = c.WritePrometheus(w)
+}
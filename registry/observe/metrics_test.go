@@ -0,0 +1,35 @@
+package observe
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ecwid/gosnap/registry"
+	"github.com/ecwid/gosnap/registry/registrytest"
+)
+
+func TestMetrics(t *testing.T) {
+	collector := NewCollector()
+	next := registrytest.New()
+	next.Put("a", registry.Object{Body: []byte("body")})
+	next.Put("b", registry.Object{Body: []byte("body")})
+	r := registry.Wrap(next, Metrics(collector))
+	_, _ = r.Head("missing")
+	_, _ = r.Pull("a")
+	_, _ = r.Pull("b")
+
+	var out strings.Builder
+	if err := collector.WritePrometheus(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`gosnap_registry_requests_total{op="head",result="no_such_key"} 1`,
+		`gosnap_registry_requests_total{op="pull",result="ok"} 2`,
+		`gosnap_registry_bytes_total{op="pull"} 8`,
+		`gosnap_registry_request_duration_seconds_count{op="pull"} 2`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Error("missing", line, "in", out.String())
+		}
+	}
+}
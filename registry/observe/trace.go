@@ -0,0 +1,34 @@
+package observe
+
+import (
+	"time"
+
+	"github.com/ecwid/gosnap/registry"
+)
+
+// Span is the subset of an OpenTelemetry span used by Tracing
+type Span interface {
+	SetAttribute(key string, value any)
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts spans, adapt an OpenTelemetry tracer to it to export registry spans
+type Tracer interface {
+	Start(name string) Span
+}
+
+// Tracing starts a span named gosnap.registry.<op> for every registry operation
+func Tracing(tracer Tracer) registry.Middleware {
+	return registry.Observe(func(op registry.Op, key string) func(int, time.Duration, error) {
+		span := tracer.Start("gosnap.registry." + string(op))
+		span.SetAttribute("gosnap.key", key)
+		return func(size int, _ time.Duration, err error) {
+			span.SetAttribute("gosnap.size", size)
+			if err != nil {
+				span.RecordError(err)
+			}
+			span.End()
+		}
+	})
+}
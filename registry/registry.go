@@ -7,6 +7,33 @@ import (
 
 var ErrNoSuchKey = errors.New("no such key")
 
+// ErrTransient marks failures worth retrying like throttling, 5xx responses and timeouts
+var ErrTransient = errors.New("transient registry failure")
+
+type temporary interface {
+	Temporary() bool
+}
+
+type timeout interface {
+	Timeout() bool
+}
+
+// IsTransient reports whether the operation may succeed if retried
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, ErrNoSuchKey) {
+		return false
+	}
+	if errors.Is(err, ErrTransient) {
+		return true
+	}
+	var t timeout
+	if errors.As(err, &t) && t.Timeout() {
+		return true
+	}
+	var tmp temporary
+	return errors.As(err, &tmp) && tmp.Temporary()
+}
+
 type Object struct {
 	Body []byte
 	Data map[string]string
@@ -44,3 +71,28 @@ func Push(registry Abstract, key string, data any) error {
 	}
 	return registry.Push(key, *dest)
 }
+
+// Lister is implemented by registries able to enumerate their keys
+type Lister interface {
+	List(prefix string) ([]string, error)
+}
+
+// Wrapper is implemented by registries adding behaviour to another one without changing its keys
+type Wrapper interface {
+	Unwrap() Abstract
+}
+
+// AsLister finds a Lister in the chain of wrapped registries
+func AsLister(registry Abstract) (Lister, bool) {
+	for registry != nil {
+		if lister, ok := registry.(Lister); ok {
+			return lister, true
+		}
+		wrapper, ok := registry.(Wrapper)
+		if !ok {
+			break
+		}
+		registry = wrapper.Unwrap()
+	}
+	return nil, false
+}
@@ -0,0 +1,78 @@
+package registrytest
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ecwid/gosnap/registry"
+)
+
+// Conformance checks the registry stores, overwrites and lists objects like the in-memory one,
+// it expects an empty registry
+func Conformance(t *testing.T, r registry.Abstract) {
+	t.Helper()
+	if _, err := r.Head("page"); !errors.Is(err, registry.ErrNoSuchKey) {
+		t.Error("expected ErrNoSuchKey from Head", err)
+	}
+	if _, err := r.Pull("page"); !errors.Is(err, registry.ErrNoSuchKey) {
+		t.Error("expected ErrNoSuchKey from Pull", err)
+	}
+
+	objects := map[string]registry.Object{
+		"page":        {Body: []byte("first"), Data: map[string]string{"hash": "1"}},
+		"page/1":      {Body: []byte("nested"), Data: map[string]string{"hash": "2"}},
+		"other/index": {Body: []byte("[]")},
+	}
+	for key, value := range objects {
+		if err := r.Push(key, value); err != nil {
+			t.Fatal(key, err)
+		}
+	}
+	objects["page"] = registry.Object{Body: []byte("second"), Data: map[string]string{"hash": "3"}}
+	if err := r.Push("page", objects["page"]); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, expected := range objects {
+		obj, err := r.Pull(key)
+		if err != nil || string(obj.Body) != string(expected.Body) {
+			t.Error("unexpected object", key, obj, err)
+			continue
+		}
+		data, err := r.Head(key)
+		if err != nil {
+			t.Error(key, err)
+		}
+		for k, v := range expected.Data {
+			if obj.Data[k] != v || data[k] != v {
+				t.Error("unexpected metadata", key, k, obj.Data, data)
+			}
+		}
+	}
+
+	if err := registry.PushStream(r, "stream", strings.NewReader("streamed"), nil); err != nil {
+		t.Fatal(err)
+	}
+	body, _, err := registry.PullStream(r, "stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, _ := io.ReadAll(body)
+	body.Close()
+	if string(value) != "streamed" {
+		t.Error("unexpected streamed body", string(value))
+	}
+
+	lister, ok := r.(registry.Lister)
+	if !ok {
+		return
+	}
+	keys, err := lister.List("page")
+	sort.Strings(keys)
+	if err != nil || strings.Join(keys, ",") != "page,page/1" {
+		t.Error("unexpected listed keys", keys, err)
+	}
+}
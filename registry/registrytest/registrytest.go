@@ -0,0 +1,166 @@
+// Package registrytest provides an in-memory registry for tests of registries and their users
+package registrytest
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ecwid/gosnap/registry"
+)
+
+// Registry keeps objects in memory, counts calls and fails them on demand
+type Registry struct {
+	mu       sync.Mutex
+	objects  map[string]registry.Object
+	calls    map[string]int
+	failures int
+	err      error
+}
+
+func New() *Registry {
+	return &Registry{objects: map[string]registry.Object{}, calls: map[string]int{}}
+}
+
+func copyData(data map[string]string) map[string]string {
+	value := map[string]string{}
+	for k, v := range data {
+		value[k] = v
+	}
+	return value
+}
+
+// Fail makes the next n calls return err
+func (r *Registry) Fail(n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures, r.err = n, err
+}
+
+// Calls returns the number of calls of the op like "head", "pull", "push" or "list"
+func (r *Registry) Calls(op string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls[op]
+}
+
+// Object returns the stored object bypassing call counters and failures
+func (r *Registry) Object(key string) (registry.Object, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	obj, ok := r.objects[key]
+	return registry.Object{Body: obj.Body, Data: copyData(obj.Data)}, ok
+}
+
+// Put stores the object bypassing call counters and failures
+func (r *Registry) Put(key string, value registry.Object) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.objects[key] = registry.Object{Body: value.Body, Data: copyData(value.Data)}
+}
+
+// call must be called with the lock held
+func (r *Registry) call(op string) error {
+	r.calls[op]++
+	if r.failures > 0 {
+		r.failures--
+		return r.err
+	}
+	return nil
+}
+
+func (r *Registry) Head(key string) (map[string]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.call("head"); err != nil {
+		return nil, err
+	}
+	obj, ok := r.objects[key]
+	if !ok {
+		return nil, registry.ErrNoSuchKey
+	}
+	return copyData(obj.Data), nil
+}
+
+func (r *Registry) Pull(key string) (*registry.Object, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.call("pull"); err != nil {
+		return nil, err
+	}
+	obj, ok := r.objects[key]
+	if !ok {
+		return nil, registry.ErrNoSuchKey
+	}
+	return &registry.Object{Body: obj.Body, Data: copyData(obj.Data)}, nil
+}
+
+func (r *Registry) Push(key string, value registry.Object) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.call("push"); err != nil {
+		return err
+	}
+	r.objects[key] = registry.Object{Body: value.Body, Data: copyData(value.Data)}
+	return nil
+}
+
+func (r *Registry) List(prefix string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.call("list"); err != nil {
+		return nil, err
+	}
+	var keys []string
+	for key := range r.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (r *Registry) Resolve(key string) string {
+	return "mem://" + key
+}
+
+// Streaming returns the registry implementing registry.Streamer, stream calls are counted
+// as "pullstream" and "pushstream"
+func (r *Registry) Streaming() registry.Abstract {
+	return streaming{r}
+}
+
+type streaming struct {
+	*Registry
+}
+
+func (s streaming) PullStream(key string) (io.ReadCloser, map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.call("pullstream"); err != nil {
+		return nil, nil, err
+	}
+	obj, ok := s.objects[key]
+	if !ok {
+		return nil, nil, registry.ErrNoSuchKey
+	}
+	return io.NopCloser(bytes.NewReader(obj.Body)), copyData(obj.Data), nil
+}
+
+func (s streaming) PushStream(key string, body io.Reader, data map[string]string) error {
+	s.mu.Lock()
+	if err := s.call("pushstream"); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.mu.Unlock()
+	value, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	s.Put(key, registry.Object{Body: value, Data: data})
+	return nil
+}
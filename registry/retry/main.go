@@ -0,0 +1,132 @@
+// Package retry retries transient failures of another registry
+package retry
+
+import (
+	"io"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/ecwid/gosnap/registry"
+)
+
+// Metrics counts retries, it's safe to share between registries
+type Metrics struct {
+	// Retries is the number of repeated attempts
+	Retries atomic.Int64
+	// Exhausted is the number of operations failed after all attempts
+	Exhausted atomic.Int64
+}
+
+type Options struct {
+	// Attempts including the first one, 4 by default
+	Attempts int
+	// Base delay doubled after each attempt, 100ms by default
+	Base time.Duration
+	// Max delay, 5s by default
+	Max time.Duration
+	// Retryable decides which errors are retried, registry.IsTransient by default
+	Retryable func(error) bool
+	// Metrics is optional
+	Metrics *Metrics
+}
+
+type retryRegistry struct {
+	next registry.Abstract
+	opts Options
+}
+
+// NewRegistry retries failed operations of next with exponential backoff and full jitter.
+// registry.ErrNoSuchKey is never retried by default.
+func NewRegistry(next registry.Abstract, opts Options) registry.Abstract {
+	if opts.Attempts <= 0 {
+		opts.Attempts = 4
+	}
+	if opts.Base <= 0 {
+		opts.Base = time.Millisecond * 100
+	}
+	if opts.Max <= 0 {
+		opts.Max = time.Second * 5
+	}
+	if opts.Retryable == nil {
+		opts.Retryable = registry.IsTransient
+	}
+	return retryRegistry{next: next, opts: opts}
+}
+
+func (r retryRegistry) backoff(attempt int) time.Duration {
+	delay := r.opts.Base << attempt
+	if delay <= 0 || delay > r.opts.Max {
+		delay = r.opts.Max
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+func (r retryRegistry) do(op func() error) (err error) {
+	for attempt := 0; attempt < r.opts.Attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(r.backoff(attempt - 1))
+			if r.opts.Metrics != nil {
+				r.opts.Metrics.Retries.Add(1)
+			}
+		}
+		if err = op(); err == nil || !r.opts.Retryable(err) {
+			return err
+		}
+	}
+	if r.opts.Metrics != nil {
+		r.opts.Metrics.Exhausted.Add(1)
+	}
+	return err
+}
+
+func (r retryRegistry) Head(key string) (data map[string]string, err error) {
+	err = r.do(func() error {
+		data, err = r.next.Head(key)
+		return err
+	})
+	return data, err
+}
+
+func (r retryRegistry) Pull(key string) (object *registry.Object, err error) {
+	err = r.do(func() error {
+		object, err = r.next.Pull(key)
+		return err
+	})
+	return object, err
+}
+
+func (r retryRegistry) Push(key string, value registry.Object) error {
+	return r.do(func() error {
+		return r.next.Push(key, value)
+	})
+}
+
+func (r retryRegistry) Resolve(key string) string {
+	return r.next.Resolve(key)
+}
+
+func (r retryRegistry) Unwrap() registry.Abstract {
+	return r.next
+}
+
+// PullStream retries opening the stream, reading the body isn't retried
+func (r retryRegistry) PullStream(key string) (body io.ReadCloser, data map[string]string, err error) {
+	err = r.do(func() error {
+		body, data, err = registry.PullStream(r.next, key)
+		return err
+	})
+	return body, data, err
+}
+
+// PushStream is attempted once as the body can't be read again
+func (r retryRegistry) PushStream(key string, body io.Reader, data map[string]string) error {
+	return registry.PushStream(r.next, key, body, data)
+}
+
+// Middleware retries failed operations of the wrapped registry, see NewRegistry
+func Middleware(opts Options) registry.Middleware {
+	return func(next registry.Abstract) registry.Abstract {
+		return NewRegistry(next, opts)
+	}
+}
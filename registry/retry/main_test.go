@@ -0,0 +1,51 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ecwid/gosnap/registry"
+	"github.com/ecwid/gosnap/registry/registrytest"
+)
+
+func TestRetry(t *testing.T) {
+	var (
+		transient = errors.Join(registry.ErrTransient, errors.New("503"))
+		metrics   = new(Metrics)
+		next      = registrytest.New()
+		r         = NewRegistry(next, Options{Base: time.Millisecond, Metrics: metrics})
+	)
+	next.Put("key", registry.Object{})
+	next.Fail(2, transient)
+	if _, err := r.Head("key"); err != nil {
+		t.Error("expected success after retries", err)
+	}
+	if metrics.Retries.Load() != 2 {
+		t.Error("expected 2 retries", metrics.Retries.Load())
+	}
+
+	next.Fail(10, transient)
+	if _, err := r.Head("key"); !errors.Is(err, registry.ErrTransient) || metrics.Exhausted.Load() != 1 {
+		t.Error("expected exhausted retries", err, metrics.Exhausted.Load())
+	}
+	next.Fail(0, nil)
+	if _, err := r.Pull("missing"); !errors.Is(err, registry.ErrNoSuchKey) || metrics.Retries.Load() != 5 {
+		t.Error("no such key must not be retried", err, metrics.Retries.Load())
+	}
+}
+
+func TestRetryForwards(t *testing.T) {
+	next := registrytest.New()
+	r := NewRegistry(next.Streaming(), Options{Base: time.Millisecond})
+	next.Put("key", registry.Object{Body: []byte("body")})
+	next.Fail(1, registry.ErrTransient)
+	body, _, err := registry.PullStream(r, "key")
+	if err != nil || next.Calls("pullstream") != 2 {
+		t.Fatal("expected retried stream", err, next.Calls("pullstream"))
+	}
+	body.Close()
+	if _, ok := registry.AsLister(r); !ok {
+		t.Error("expected lister found through retries")
+	}
+}
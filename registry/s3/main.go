@@ -1,40 +1,110 @@
 package s3
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/ecwid/gosnap/registry"
 )
 
 type s3registry struct {
-	s3     *s3.S3
-	bucket string
+	s3       *s3.S3
+	uploader *s3manager.Uploader
+	bucket   string
+	opts     Options
 }
 
+type Options struct {
+	// PresignExpiry makes Resolve return presigned urls valid for the duration,
+	// so the bucket doesn't have to be public
+	PresignExpiry time.Duration
+	// ACL of pushed objects, empty omits it for buckets with ACLs disabled (bucket owner enforced)
+	ACL string
+	// Region of the bucket, us-east-1 by default
+	Region string
+}
+
+// MaxPresignExpiry is the longest lifetime of a presigned url S3 accepts
+const MaxPresignExpiry = 7 * 24 * time.Hour
+
+// NewRegistry pushes public-read objects
 func NewRegistry(id, secret, bucket string) registry.Abstract {
-	var value = s3registry{bucket: bucket}
-	var sess, err = session.NewSession(&aws.Config{Region: aws.String(endpoints.UsEast1RegionID)})
+	value, err := NewRegistryWithOptions(id, secret, bucket, Options{ACL: s3.BucketCannedACLPublicRead})
 	if err != nil {
 		panic(err)
 	}
+	return value
+}
+
+func NewRegistryWithOptions(id, secret, bucket string, opts Options) (registry.Abstract, error) {
+	if opts.PresignExpiry > MaxPresignExpiry {
+		return nil, fmt.Errorf("presign expiry %s is longer than %s", opts.PresignExpiry, MaxPresignExpiry)
+	}
+	if opts.Region == "" {
+		opts.Region = endpoints.UsEast1RegionID
+	}
+	var value = s3registry{bucket: bucket, opts: opts}
+	var sess, err = session.NewSession(&aws.Config{Region: aws.String(opts.Region)})
+	if err != nil {
+		return nil, err
+	}
 	creds := credentials.NewStaticCredentials(id, secret, "")
 	value.s3 = s3.New(sess, &aws.Config{Credentials: creds})
-	return value
+	value.uploader = s3manager.NewUploaderWithClient(value.s3)
+	return value, nil
+}
+
+func (c s3registry) acl() *string {
+	if c.opts.ACL == "" {
+		return nil
+	}
+	return aws.String(c.opts.ACL)
 }
 
 func (c s3registry) Resolve(key string) string {
+	if c.opts.PresignExpiry > 0 {
+		req, _ := c.s3.GetObjectRequest(&s3.GetObjectInput{
+			Bucket: aws.String(c.bucket),
+			Key:    aws.String(key),
+		})
+		url, err := req.Presign(c.opts.PresignExpiry)
+		if err == nil {
+			return url
+		}
+		// the public url is only useful for diagnostics of a private bucket
+		slog.Error("can't presign s3 url", "key", key, "error", err)
+	}
 	return fmt.Sprintf("https://%s.s3.amazonaws.com/%s", c.bucket, key)
 }
 
+func (c s3registry) List(prefix string) ([]string, error) {
+	var keys []string
+	err := c.s3.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(c.bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, aws.StringValue(object.Key))
+		}
+		return true
+	})
+	return keys, transientErr(err)
+}
+
 func noSuchKeyErr(err error) error {
 	if aerr, ok := err.(awserr.Error); ok {
 		switch aerr.Code() {
@@ -42,6 +112,13 @@ func noSuchKeyErr(err error) error {
 			return registry.ErrNoSuchKey
 		}
 	}
+	return transientErr(err)
+}
+
+func transientErr(err error) error {
+	if err != nil && (request.IsErrorRetryable(err) || request.IsErrorThrottle(err)) {
+		return errors.Join(registry.ErrTransient, err)
+	}
 	return err
 }
 
@@ -53,47 +130,71 @@ func (c s3registry) Head(key string) (map[string]string, error) {
 	if err != nil {
 		return nil, noSuchKeyErr(err)
 	}
-	data := map[string]string{
-		"last-modified-unix": fmt.Sprint(head.LastModified.Unix()),
+	return metadata(head.Metadata, head.LastModified), nil
+}
+
+func metadata(values map[string]*string, lastModified *time.Time) map[string]string {
+	data := map[string]string{}
+	if lastModified != nil {
+		data["last-modified-unix"] = fmt.Sprint(lastModified.Unix())
 	}
-	for key, value := range head.Metadata {
+	for key, value := range values {
 		if value != nil {
 			data[key] = *value
 		}
 	}
-	return data, nil
+	return data
 }
 
-func (c s3registry) Pull(key string) (*registry.Object, error) {
-	var data = new(registry.Object)
+func (c s3registry) PullStream(key string) (io.ReadCloser, map[string]string, error) {
 	output, err := c.s3.GetObject(&s3.GetObjectInput{
 		Bucket: aws.String(c.bucket),
 		Key:    aws.String(key),
 	})
 	if err != nil {
-		return nil, noSuchKeyErr(err)
+		return nil, nil, noSuchKeyErr(err)
 	}
-	var buf bytes.Buffer
-	if _, err = io.Copy(&buf, output.Body); err != nil {
-		return nil, err
+	return output.Body, metadata(output.Metadata, output.LastModified), nil
+}
+
+// PushStream uploads the body in parts so it's never buffered whole
+func (c s3registry) PushStream(key string, body io.Reader, data map[string]string) error {
+	reader := bufio.NewReader(body)
+	head, _ := reader.Peek(512)
+	input := &s3manager.UploadInput{
+		Bucket:      aws.String(c.bucket),
+		Key:         aws.String(key),
+		ACL:         c.acl(),
+		Body:        reader,
+		ContentType: aws.String(http.DetectContentType(head)),
+		Metadata:    map[string]*string{},
 	}
-	data.Body = buf.Bytes()
-	data.Data = map[string]string{
-		"last-modified-unix": fmt.Sprint(output.LastModified.Unix()),
+	for k, v := range data {
+		value := v
+		input.Metadata[k] = &value
 	}
-	for key, value := range output.Metadata {
-		if value != nil {
-			data.Data[key] = *value
-		}
+	_, err := c.uploader.Upload(input)
+	return transientErr(err)
+}
+
+func (c s3registry) Pull(key string) (*registry.Object, error) {
+	body, data, err := c.PullStream(key)
+	if err != nil {
+		return nil, err
 	}
-	return data, nil
+	defer body.Close()
+	var buf bytes.Buffer
+	if _, err = io.Copy(&buf, body); err != nil {
+		return nil, transientErr(err)
+	}
+	return &registry.Object{Body: buf.Bytes(), Data: data}, nil
 }
 
 func (c s3registry) Push(key string, object registry.Object) error {
 	req := &s3.PutObjectInput{
 		Bucket:   aws.String(c.bucket),
 		Key:      aws.String(key),
-		ACL:      aws.String(s3.BucketCannedACLPublicRead),
+		ACL:      c.acl(),
 		Metadata: map[string]*string{},
 	}
 	if object.Body != nil {
@@ -106,5 +207,5 @@ func (c s3registry) Push(key string, object registry.Object) error {
 		req.Metadata[k] = &value
 	}
 	_, err := c.s3.PutObject(req)
-	return err
+	return transientErr(err)
 }
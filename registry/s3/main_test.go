@@ -0,0 +1,35 @@
+package s3
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolvePresigned(t *testing.T) {
+	r, err := NewRegistryWithOptions("id", "secret", "bucket", Options{PresignExpiry: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := r.Resolve("2023/page")
+	if !strings.HasPrefix(url, "https://bucket.s3.amazonaws.com/2023/page?") || !strings.Contains(url, "X-Amz-Expires=3600") {
+		t.Error("expected presigned url", url)
+	}
+	url = NewRegistry("id", "secret", "bucket").Resolve("2023/page")
+	if url != "https://bucket.s3.amazonaws.com/2023/page" {
+		t.Error("expected public url", url)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	if _, err := NewRegistryWithOptions("id", "secret", "bucket", Options{PresignExpiry: 8 * 24 * time.Hour}); err == nil {
+		t.Error("expected too long presign expiry rejected")
+	}
+	r, _ := NewRegistryWithOptions("id", "secret", "bucket", Options{})
+	if acl := r.(s3registry).acl(); acl != nil {
+		t.Error("expected acl omitted", *acl)
+	}
+	if acl := NewRegistry("id", "secret", "bucket").(s3registry).acl(); acl == nil || *acl != "public-read" {
+		t.Error("expected public-read acl", acl)
+	}
+}
@@ -0,0 +1,37 @@
+package registry
+
+import (
+	"bytes"
+	"io"
+)
+
+// Streamer is implemented by registries transferring bodies without buffering them in memory
+type Streamer interface {
+	// PullStream returns the body reader the caller has to close
+	PullStream(key string) (io.ReadCloser, map[string]string, error)
+	PushStream(key string, body io.Reader, data map[string]string) error
+}
+
+// PullStream reads the object body as a stream if the registry supports it
+func PullStream(registry Abstract, key string) (io.ReadCloser, map[string]string, error) {
+	if s, ok := registry.(Streamer); ok {
+		return s.PullStream(key)
+	}
+	object, err := registry.Pull(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return io.NopCloser(bytes.NewReader(object.Body)), object.Data, nil
+}
+
+// PushStream writes the object body as a stream if the registry supports it
+func PushStream(registry Abstract, key string, body io.Reader, data map[string]string) error {
+	if s, ok := registry.(Streamer); ok {
+		return s.PushStream(key, body, data)
+	}
+	value, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	return registry.Push(key, Object{Body: value, Data: data})
+}
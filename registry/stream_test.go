@@ -0,0 +1,67 @@
+package registry_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ecwid/gosnap/registry"
+	"github.com/ecwid/gosnap/registry/registrytest"
+)
+
+func TestStreamDispatch(t *testing.T) {
+	plain := registrytest.New()
+	for _, r := range []registry.Abstract{plain, registrytest.New().Streaming()} {
+		if err := registry.PushStream(r, "key", strings.NewReader("body"), map[string]string{"Hash": "1"}); err != nil {
+			t.Fatal(err)
+		}
+		body, data, err := registry.PullStream(r, "key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, _ := io.ReadAll(body)
+		body.Close()
+		if string(value) != "body" || data["Hash"] != "1" {
+			t.Error("unexpected object", string(value), data)
+		}
+	}
+	if plain.Calls("push") != 1 || plain.Calls("pull") != 1 {
+		t.Error("expected buffered fallback", plain.Calls("push"), plain.Calls("pull"))
+	}
+}
+
+func TestStreamDispatchStreamer(t *testing.T) {
+	r := registrytest.New()
+	streaming := r.Streaming()
+	_ = registry.PushStream(streaming, "key", strings.NewReader("body"), nil)
+	body, _, err := registry.PullStream(streaming, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body.Close()
+	if r.Calls("pushstream") != 1 || r.Calls("pullstream") != 1 || r.Calls("push")+r.Calls("pull") != 0 {
+		t.Error("expected streams", r.Calls("pushstream"), r.Calls("pullstream"))
+	}
+}
+
+func TestWrappersForward(t *testing.T) {
+	var sizes []int
+	inner := registrytest.New()
+	r := registry.Wrap(inner.Streaming(), registry.Observe(func(op registry.Op, key string) func(int, time.Duration, error) {
+		return func(size int, _ time.Duration, _ error) { sizes = append(sizes, size) }
+	}))
+	if _, ok := registry.AsLister(r); !ok {
+		t.Error("expected lister found through the middleware")
+	}
+	_ = registry.PushStream(r, "key", strings.NewReader("body"), nil)
+	body, _, _ := registry.PullStream(r, "key")
+	_, _ = io.ReadAll(body)
+	body.Close()
+	if inner.Calls("pushstream") != 1 || inner.Calls("pullstream") != 1 {
+		t.Error("expected streams forwarded", inner.Calls("pushstream"), inner.Calls("pullstream"))
+	}
+	if len(sizes) != 2 || sizes[0] != 4 || sizes[1] != 4 {
+		t.Error("expected observed stream sizes", sizes)
+	}
+}
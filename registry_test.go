@@ -0,0 +1,27 @@
+package gosnap
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/ecwid/gosnap/registry/registrytest"
+)
+
+func newMemRegistry() *registrytest.Registry {
+	return registrytest.New()
+}
+
+// testImage draws a black stripe of the given width on a white background
+func testImage(w, h, stripe int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < stripe {
+				img.Set(x, y, color.Black)
+			} else {
+				img.Set(x, y, color.White)
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,181 @@
+package gosnap
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"image"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/ecwid/gosnap/registry"
+	"golang.org/x/image/draw"
+)
+
+// ReportOptions configures Report
+type ReportOptions struct {
+	// Title of the page, the run id by default
+	Title string
+	// ThumbnailWidth of embedded images, 240 by default
+	ThumbnailWidth int
+	// Filters are Change.Data keys to filter changes by, like browser and os, all keys by default
+	Filters []string
+}
+
+type reportFilter struct {
+	Key    string
+	Values []string
+}
+
+type reportChange struct {
+	Change
+	Score    int
+	Time     string
+	Meta     string
+	Baseline reportImage
+	Target   reportImage
+	Overlay  reportImage
+}
+
+type reportImage struct {
+	URL       string
+	Thumbnail template.URL
+}
+
+// Report writes a self-contained html page of the run's changes with embedded thumbnails,
+// diff scores and metadata, full size images are linked via the registry's Resolve
+func Report(runID string, w io.Writer, opts ReportOptions) error {
+	if opts.Title == "" {
+		opts.Title = runID
+	}
+	if opts.ThumbnailWidth <= 0 {
+		opts.ThumbnailWidth = 240
+	}
+
+	var batch = new(Batch)
+	if err := batch.Pull(runID); err != nil && !errors.Is(err, registry.ErrNoSuchKey) {
+		return err
+	}
+	sort.Slice(batch.Changes, func(i, j int) bool {
+		return batch.Changes[i].Key < batch.Changes[j].Key
+	})
+
+	var (
+		changes = make([]reportChange, 0, len(batch.Changes))
+		values  = map[string]map[string]bool{}
+	)
+	for _, change := range batch.Changes {
+		meta, _ := json.Marshal(change.Data)
+		changes = append(changes, reportChange{
+			Change:   change,
+			Score:    change.XorHash.onesCount(),
+			Time:     time.Unix(change.Ts, 0).UTC().Format(time.DateTime),
+			Meta:     string(meta),
+			Baseline: newReportImage(change.Key, opts.ThumbnailWidth),
+			Target:   newReportImage(change.Target, opts.ThumbnailWidth),
+			Overlay:  newReportImage(change.Overlay, opts.ThumbnailWidth),
+		})
+		for k, v := range change.Data {
+			if values[k] == nil {
+				values[k] = map[string]bool{}
+			}
+			values[k][v] = true
+		}
+	}
+
+	keys := opts.Filters
+	if keys == nil {
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+	filters := []reportFilter{}
+	for _, k := range keys {
+		filter := reportFilter{Key: k}
+		for v := range values[k] {
+			filter.Values = append(filter.Values, v)
+		}
+		sort.Strings(filter.Values)
+		if len(filter.Values) > 0 {
+			filters = append(filters, filter)
+		}
+	}
+
+	return reportTemplate.Execute(w, map[string]any{
+		"Title":   opts.Title,
+		"Run":     runID,
+		"Changes": changes,
+		"Filters": filters,
+	})
+}
+
+// newReportImage embeds a scaled down snapshot, the thumbnail is left out if the snapshot can't be pulled
+func newReportImage(key string, width int) reportImage {
+	value := reportImage{URL: defaultRegistry.Resolve(key)}
+	var snapshot = new(Snapshot)
+	if err := snapshot.Pull(key); err != nil || snapshot.Value == nil {
+		return value
+	}
+	bounds := snapshot.Value.Bounds()
+	if bounds.Dx() > width {
+		thumbnail := image.NewRGBA(image.Rect(0, 0, width, max(bounds.Dy()*width/bounds.Dx(), 1)))
+		draw.ApproxBiLinear.Scale(thumbnail, thumbnail.Bounds(), snapshot.Value, bounds, draw.Src, nil)
+		snapshot.Value = thumbnail
+	}
+	body, err := encodePng(snapshot.Value)
+	if err != nil {
+		return value
+	}
+	value.Thumbnail = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(body))
+	return value
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: .4em .8em; border-bottom: 1px solid #ddd; text-align: left; vertical-align: top; }
+img { border: 1px solid #ccc; }
+.filters label { margin-right: 1em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{len .Changes}} changes of run {{.Run}}</p>
+{{if .Filters}}<p class="filters">{{range .Filters}}
+<label>{{.Key}} <select data-key="{{.Key}}" onchange="filter()"><option value="">all</option>{{range .Values}}<option>{{.}}</option>{{end}}</select></label>{{end}}
+</p>{{end}}
+<table>
+<tr><th>baseline</th><th>score</th><th>metadata</th><th>expected</th><th>actual</th><th>overlay</th></tr>
+{{range .Changes}}<tr class="change" data-meta="{{.Meta}}">
+<td>{{.Key}}<br><small>{{.Time}}</small></td>
+<td>{{.Score}}</td>
+<td>{{range $k, $v := .Data}}{{$k}}: {{$v}}<br>{{end}}</td>
+<td>{{template "image" .Baseline}}</td>
+<td>{{template "image" .Target}}</td>
+<td>{{template "image" .Overlay}}</td>
+</tr>{{else}}<tr><td colspan="6">no changes</td></tr>{{end}}
+</table>
+<script>
+function filter() {
+	var selects = document.querySelectorAll(".filters select");
+	document.querySelectorAll("tr.change").forEach(function (row) {
+		var meta = JSON.parse(row.dataset.meta) || {};
+		var visible = Array.prototype.every.call(selects, function (s) {
+			return s.value === "" || meta[s.dataset.key] === s.value;
+		});
+		row.style.display = visible ? "" : "none";
+	});
+}
+</script>
+</body>
+</html>
+{{define "image"}}<a href="{{.URL}}">{{if .Thumbnail}}<img src="{{.Thumbnail}}">{{else}}open{{end}}</a>{{end}}
+`))
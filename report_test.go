@@ -0,0 +1,34 @@
+package gosnap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	SetRegistry(newMemRegistry())
+	matcher := NewMatcher("run").ApprovalEnabled(false, "")
+	_ = matcher.New("page").Compare(testImage(640, 64, 160))
+	if err := matcher.New("page").Metadata("browser", "firefox").Compare(testImage(640, 64, 480)); err == nil {
+		t.Fatal("expected a change")
+	}
+
+	var s strings.Builder
+	if err := Report("run", &s, ReportOptions{Filters: []string{"browser", "os"}}); err != nil {
+		t.Fatal(err)
+	}
+	html := s.String()
+	for _, value := range []string{"1 changes of run run", `data-key="browser"`, "<option>firefox</option>", "data:image/png;base64,"} {
+		if !strings.Contains(html, value) {
+			t.Error("expected in report", value)
+		}
+	}
+	if strings.Contains(html, `data-key="os"`) {
+		t.Error("unexpected filter without values")
+	}
+
+	s.Reset()
+	if err := Report("missing", &s, ReportOptions{}); err != nil || !strings.Contains(s.String(), "no changes") {
+		t.Error("expected empty report", err)
+	}
+}
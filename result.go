@@ -0,0 +1,49 @@
+package gosnap
+
+import "time"
+
+type MatchStatus string
+
+const (
+	// StatusMatched the target matches the baseline
+	StatusMatched MatchStatus = "matched"
+	// StatusApproved the target differs from the baseline but the diff is approved
+	StatusApproved MatchStatus = "approved"
+	// StatusNew there was no baseline so the target is published as a new one
+	StatusNew MatchStatus = "new"
+	// StatusUpdated the baseline is replaced by the target
+	StatusUpdated MatchStatus = "updated"
+	// StatusChanged the target differs from the baseline
+	StatusChanged MatchStatus = "changed"
+)
+
+type Timings struct {
+	Hash      time.Duration
+	Baseline  time.Duration
+	Approvals time.Duration
+	Upload    time.Duration
+}
+
+type MatchResult struct {
+	Status MatchStatus
+	// Key of the baseline snapshot
+	Key string
+	// Distance between the baseline and the target hashes
+	Distance int
+	// Approvals explaining the diff of an approved target
+	Approvals []Approval
+	// Change is set for changed and approved targets
+	Change  *Change
+	Timings Timings
+}
+
+// Err returns the result in the form Match and Compare report it
+func (r MatchResult) Err() error {
+	switch r.Status {
+	case StatusNew, StatusUpdated:
+		return Published{Key: r.Key}
+	case StatusChanged:
+		return *r.Change
+	}
+	return nil
+}
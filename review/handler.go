@@ -0,0 +1,273 @@
+// Package review serves a web UI to review change batches of runs
+package review
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ecwid/gosnap"
+	"github.com/ecwid/gosnap/registry"
+)
+
+type Options struct {
+	// Base is the path the handler is mounted at, like /gosnap
+	Base string
+	// ApprovalsKey is where approvals are stored
+	ApprovalsKey string
+	// Runs are listed on the index page
+	Runs []string
+	// User identifies the reviewer, like a header set by an authenticating proxy.
+	// It's required to post actions, the handler is read only without it.
+	User func(r *http.Request) string
+	// Matcher approves, declines and promotes the run's changes with its retention and hash settings,
+	// gosnap.NewMatcher by default. Objects are read from the registry passed to gosnap.SetRegistry.
+	Matcher func(run string) gosnap.Matcher
+}
+
+type handler struct {
+	opts Options
+	// lock serializes actions as matchers of different requests don't share their synced ops
+	lock *sync.Mutex
+}
+
+// NewHandler serves the run's changes at {Base}/{run} and a change at {Base}/{run}/{key},
+// its images at {Base}/{run}/{key}?image=baseline|target|overlay. The run is escaped
+// as a single path segment, so it may contain slashes, see ApproveURL.
+// Posting the change page from the same origin approves, declines or promotes the change.
+func NewHandler(opts Options) http.Handler {
+	opts.Base = strings.TrimSuffix(opts.Base, "/")
+	if opts.Matcher == nil {
+		opts.Matcher = gosnap.NewMatcher
+	}
+	return handler{opts: opts, lock: &sync.Mutex{}}
+}
+
+// escapeKey escapes every segment of the key keeping its slashes
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for n, segment := range segments {
+		segments[n] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// changePath is the path of the change page relative to the handler's base
+func changePath(run, key string) string {
+	return "/" + url.PathEscape(run) + "/" + escapeKey(key)
+}
+
+// ApproveURL returns a gosnap.KeyToApproveUrl implementation pointing to the handler served at base url
+func ApproveURL(base string) func(label, key string) string {
+	base = strings.TrimSuffix(base, "/")
+	return func(label, key string) string {
+		return base + changePath(label, key)
+	}
+}
+
+type changeView struct {
+	gosnap.Change
+	Score int
+	Time  string
+}
+
+func newChangeView(change gosnap.Change) changeView {
+	return changeView{
+		Change: change,
+		Score:  change.XorHash.OnesCount(),
+		Time:   time.Unix(change.Ts, 0).UTC().Format(time.DateTime),
+	}
+}
+
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.EscapedPath(), h.opts.Base), "/")
+	run, key, _ := strings.Cut(path, "/")
+	var err error
+	if run, err = url.PathUnescape(run); err == nil {
+		key, err = url.PathUnescape(key)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case run == "":
+		h.render(w, "index", map[string]any{"Base": h.opts.Base, "Runs": h.opts.Runs})
+	case key == "":
+		h.run(w, r, run)
+	case r.Method == http.MethodPost:
+		h.act(w, r, run, key)
+	case r.URL.Query().Has("image"):
+		h.image(w, run, key, r.URL.Query().Get("image"))
+	default:
+		h.change(w, run, key)
+	}
+}
+
+func (h handler) render(w http.ResponseWriter, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := templates.ExecuteTemplate(w, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeErr(w http.ResponseWriter, err error) {
+	if errors.Is(err, registry.ErrNoSuchKey) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// image serves a snapshot of the change, other registry keys aren't reachable
+func (h handler) image(w http.ResponseWriter, run, key, name string) {
+	change, err := findChange(run, key)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	switch name {
+	case "baseline":
+		key = change.Key
+	case "target":
+		key = change.Target
+	case "overlay":
+		key = change.Overlay
+	default:
+		http.Error(w, "unknown image", http.StatusBadRequest)
+		return
+	}
+	if key == "" {
+		http.Error(w, "no such image", http.StatusNotFound)
+		return
+	}
+	body, _, err := registry.PullStream(gosnap.GetRegistry(), key)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	defer body.Close()
+	reader := bufio.NewReader(body)
+	head, _ := reader.Peek(512)
+	w.Header().Set("Content-Type", http.DetectContentType(head))
+	_, _ = io.Copy(w, reader)
+}
+
+func (h handler) run(w http.ResponseWriter, r *http.Request, run string) {
+	var batch = new(gosnap.Batch)
+	if err := batch.Pull(run); err != nil && !errors.Is(err, registry.ErrNoSuchKey) {
+		writeErr(w, err)
+		return
+	}
+	views := make([]changeView, 0, len(batch.Changes))
+	for _, change := range batch.Changes {
+		views = append(views, newChangeView(change))
+	}
+	h.render(w, "run", map[string]any{
+		"Base":    h.opts.Base,
+		"Run":     run,
+		"Changes": views,
+		"Notice":  r.URL.Query().Get("notice"),
+	})
+}
+
+func findChange(run, key string) (gosnap.Change, error) {
+	var batch = new(gosnap.Batch)
+	if err := batch.Pull(run); err != nil {
+		return gosnap.Change{}, err
+	}
+	for _, change := range batch.Changes {
+		if change.Key == key {
+			return change, nil
+		}
+	}
+	return gosnap.Change{}, registry.ErrNoSuchKey
+}
+
+func (h handler) change(w http.ResponseWriter, run, key string) {
+	change, err := findChange(run, key)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	view := newChangeView(change)
+	h.render(w, "change", map[string]any{
+		"Base":   h.opts.Base,
+		"Run":    run,
+		"Change": view.Change,
+		"Score":  view.Score,
+		"Time":   view.Time,
+	})
+}
+
+// sameOrigin tells if the request is posted by a page of the handler's host,
+// browsers send Origin or at least Referer with form posts
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		origin = r.Referer()
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && u.Host == r.Host
+}
+
+func (h handler) act(w http.ResponseWriter, r *http.Request, run, key string) {
+	if h.opts.User == nil {
+		http.Error(w, "reviewer identification isn't configured", http.StatusForbidden)
+		return
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "cross origin request", http.StatusForbidden)
+		return
+	}
+	user := h.opts.User(r)
+	if user == "" {
+		http.Error(w, "reviewer is unknown", http.StatusUnauthorized)
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	change, err := findChange(run, key)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	approval := change.Approval(user)
+	approval.Comment = r.PostFormValue("comment")
+	approval.Ticket = r.PostFormValue("ticket")
+
+	matcher := h.opts.Matcher(run)
+	action := r.PostFormValue("action")
+	switch action {
+	case "approve":
+		err = matcher.Synced().AcceptApproval(h.opts.ApprovalsKey, approval)
+	case "decline":
+		err = matcher.Synced().DeclineApproval(h.opts.ApprovalsKey, approval)
+		if errors.Is(err, registry.ErrNoSuchKey) {
+			err = nil
+		}
+		if err == nil {
+			err = matcher.Synced().DeleteChanges(run, &change.Key)
+		}
+	case "promote":
+		err = matcher.PromoteChanges(user, func(value gosnap.Change) bool {
+			return value.Key == change.Key
+		})
+	default:
+		http.Error(w, "unknown action", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+	notice := fmt.Sprintf("%s: %s by %s", change.Key, action, user)
+	http.Redirect(w, r, h.opts.Base+"/"+url.PathEscape(run)+"?notice="+url.QueryEscape(notice), http.StatusSeeOther)
+}
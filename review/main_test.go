@@ -0,0 +1,185 @@
+package review
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ecwid/gosnap"
+	"github.com/ecwid/gosnap/registry/disk"
+)
+
+func testImage(stripe int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 64; y++ {
+			img.Set(x, y, color.White)
+			if x < stripe {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	return img
+}
+
+func forwardedUser(r *http.Request) string {
+	return r.Header.Get("X-Forwarded-User")
+}
+
+func post(t *testing.T, server *httptest.Server, path, action string) *http.Response {
+	return postFrom(t, server, server.URL, path, action)
+}
+
+func postFrom(t *testing.T, server *httptest.Server, origin, path, action string) *http.Response {
+	req, _ := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader(url.Values{"action": {action}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Forwarded-User", "reviewer")
+	req.Header.Set("Origin", origin)
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestReview(t *testing.T) {
+	store := disk.NewRegistry(t.TempDir(), "")
+	gosnap.SetRegistry(store)
+	matcher := gosnap.NewMatcher("run").ApprovalEnabled(true, "approvals")
+	if err := matcher.New("page").Compare(testImage(16)); !errors.Is(err, gosnap.ErrPublished) {
+		t.Fatal("expected a new baseline", err)
+	}
+	if err := matcher.New("page").Compare(testImage(48)); err == nil {
+		t.Fatal("expected a change")
+	}
+
+	server := httptest.NewServer(NewHandler(Options{
+		ApprovalsKey: "approvals",
+		User:         forwardedUser,
+		Matcher: func(run string) gosnap.Matcher {
+			return gosnap.NewMatcher(run).KeepHistory(5)
+		},
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/run/page")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatal("unexpected change page", resp, err)
+	}
+	resp.Body.Close()
+	for _, image := range []string{"baseline", "target", "overlay"} {
+		if resp, _ = http.Get(server.URL + "/run/page?image=" + image); resp.Header.Get("Content-Type") != "image/png" {
+			t.Error("expected png", image, resp.Header)
+		}
+		resp.Body.Close()
+	}
+	if resp = post(t, server, "/run/page", "approve"); resp.StatusCode != http.StatusSeeOther {
+		t.Fatal("unexpected approve status", resp.Status)
+	}
+	if err = matcher.New("page").Compare(testImage(48)); err != nil {
+		t.Error("expected approved change", err)
+	}
+
+	if resp = post(t, server, "/run/page", "promote"); resp.StatusCode != http.StatusSeeOther {
+		t.Fatal("unexpected promote status", resp.Status)
+	}
+	if err = matcher.ApprovalEnabled(false, "").New("page").Compare(testImage(48)); err != nil {
+		t.Error("expected promoted baseline", err)
+	}
+	if revisions, _ := gosnap.NewSyncedOps().History("page"); len(revisions) != 1 {
+		t.Error("expected the promoted baseline replaced with history", revisions)
+	}
+	if resp = post(t, server, "/run/page", "promote"); resp.StatusCode != http.StatusNotFound {
+		t.Error("expected the change removed from batch", resp.Status)
+	}
+}
+
+func TestReviewRejects(t *testing.T) {
+	gosnap.SetRegistry(disk.NewRegistry(t.TempDir(), ""))
+	matcher := gosnap.NewMatcher("run").ApprovalEnabled(true, "approvals")
+	_ = matcher.New("page").Compare(testImage(16))
+	_ = matcher.New("other").Compare(testImage(16))
+	_ = matcher.New("page").Compare(testImage(48))
+
+	readOnly := httptest.NewServer(NewHandler(Options{ApprovalsKey: "approvals"}))
+	defer readOnly.Close()
+	if resp := post(t, readOnly, "/run/page", "approve"); resp.StatusCode != http.StatusForbidden {
+		t.Error("expected posts rejected without User", resp.Status)
+	}
+
+	server := httptest.NewServer(NewHandler(Options{ApprovalsKey: "approvals", User: forwardedUser}))
+	defer server.Close()
+	if resp := postFrom(t, server, "https://evil.example.com", "/run/page", "approve"); resp.StatusCode != http.StatusForbidden {
+		t.Error("expected cross origin post rejected", resp.Status)
+	}
+	if resp := postFrom(t, server, "", "/run/page", "approve"); resp.StatusCode != http.StatusForbidden {
+		t.Error("expected post without origin rejected", resp.Status)
+	}
+
+	for path, status := range map[string]int{
+		"/run/other?image=baseline":    http.StatusNotFound,
+		"/run/page?image=approvals":    http.StatusBadRequest,
+		"/_snapshot/other":             http.StatusNotFound,
+		"/approvals/page?image=target": http.StatusNotFound,
+	} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Error("unexpected status", path, resp.Status)
+		}
+	}
+}
+
+func TestReviewEscaped(t *testing.T) {
+	gosnap.SetRegistry(disk.NewRegistry(t.TempDir(), ""))
+	const run, key = "runs/42", "TestX/sub#01 a?b%"
+	matcher := gosnap.NewMatcher(run).ApprovalEnabled(true, "approvals")
+	_ = matcher.New(key).Compare(testImage(16))
+	if err := matcher.New(key).Compare(testImage(48)); !errors.Is(err, gosnap.ErrChanged) {
+		t.Fatal("expected a change", err)
+	}
+
+	server := httptest.NewServer(NewHandler(Options{ApprovalsKey: "approvals", User: forwardedUser}))
+	defer server.Close()
+	path := "/runs%2F42/TestX/sub%2301%20a%3Fb%25"
+	if value := ApproveURL(server.URL)(run, key); value != server.URL+path {
+		t.Error("unexpected url", value)
+	}
+
+	resp, err := http.Get(server.URL + "/runs%2F42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(page), `href="`+path+`"`) {
+		t.Error("expected escaped change link", string(page))
+	}
+	if resp, _ = http.Get(server.URL + path + "?image=target"); resp.Header.Get("Content-Type") != "image/png" {
+		t.Error("expected png target", resp.Status)
+	}
+	resp.Body.Close()
+	resp = post(t, server, path, "approve")
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/runs%2F42?notice="+url.QueryEscape(key+": approve by reviewer") {
+		t.Error("unexpected approve response", resp.Status, resp.Header.Get("Location"))
+	}
+	if err = matcher.New(key).Compare(testImage(48)); err != nil {
+		t.Error("expected approved change", err)
+	}
+}
+
+func TestApproveURL(t *testing.T) {
+	if value := ApproveURL("https://example.com/review/")("run", "a/page"); value != "https://example.com/review/run/a/page" {
+		t.Error("unexpected url", value)
+	}
+}
@@ -0,0 +1,71 @@
+package review
+
+import (
+	"html/template"
+	"net/url"
+)
+
+var templates = template.Must(template.New("layout").Funcs(template.FuncMap{
+	"run": url.PathEscape,
+	"key": escapeKey,
+}).Parse(`
+{{define "header"}}<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gosnap review</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: .3em .8em; border-bottom: 1px solid #ddd; text-align: left; }
+.images { display: flex; gap: 1em; }
+.images figure { margin: 0; flex: 1; }
+.images img { max-width: 100%; border: 1px solid #ccc; }
+.notice { background: #efe; padding: .5em; }
+form.actions input[type=text] { width: 20em; }
+</style>
+</head>
+<body>
+{{end}}
+
+{{define "footer"}}</body></html>{{end}}
+
+{{define "index"}}{{template "header"}}
+<h1>Runs</h1>
+<ul>{{range .Runs}}<li><a href="{{$.Base}}/{{run .}}">{{.}}</a></li>{{end}}</ul>
+<form method="get" onsubmit="location.href='{{.Base}}/'+encodeURIComponent(this.run.value);return false">
+<input name="run" placeholder="run id"> <button>open</button>
+</form>
+{{template "footer"}}{{end}}
+
+{{define "run"}}{{template "header"}}
+<h1>Changes of {{.Run}}</h1>
+{{if .Notice}}<p class="notice">{{.Notice}}</p>{{end}}
+<table>
+<tr><th>baseline</th><th>score</th><th>metadata</th><th>changed at</th></tr>
+{{range .Changes}}<tr>
+<td><a href="{{$.Base}}/{{run $.Run}}/{{key .Key}}">{{.Key}}</a></td>
+<td>{{.Score}}</td>
+<td>{{range $k, $v := .Data}}{{$k}}={{$v}} {{end}}</td>
+<td>{{.Time}}</td>
+</tr>{{else}}<tr><td colspan="4">no changes</td></tr>{{end}}
+</table>
+{{template "footer"}}{{end}}
+
+{{define "change"}}{{template "header"}}
+<p><a href="{{.Base}}/{{run .Run}}">&larr; {{.Run}}</a></p>
+<h1>{{.Change.Key}}</h1>
+<p>score {{.Score}}, changed at {{.Time}}</p>
+<div class="images">
+<figure><img src="{{.Base}}/{{run .Run}}/{{key .Change.Key}}?image=baseline"><figcaption>baseline</figcaption></figure>
+<figure><img src="{{.Base}}/{{run .Run}}/{{key .Change.Key}}?image=target"><figcaption>target</figcaption></figure>
+<figure><img src="{{.Base}}/{{run .Run}}/{{key .Change.Key}}?image=overlay"><figcaption>overlay</figcaption></figure>
+</div>
+<form class="actions" method="post">
+<p><input type="text" name="comment" placeholder="comment"> <input type="text" name="ticket" placeholder="ticket"></p>
+<button name="action" value="approve">approve</button>
+<button name="action" value="decline">decline</button>
+<button name="action" value="promote">promote to baseline</button>
+</form>
+{{template "footer"}}{{end}}
+`))
@@ -1,9 +1,12 @@
 package gosnap
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
 	"image"
+	"image/png"
+	"io"
 	"sort"
 	"strconv"
 	"time"
@@ -46,17 +49,14 @@ func (b Snapshot) GetSize() (x int, y int) {
 	return 0, 0
 }
 
-func (s *Snapshot) decode(data registry.Object) error {
-	s.Metadata = data.Data
-	s.Hash = hashString(data.Data[dataHash])
-	var err error
-	if data.Body != nil {
-		s.Value, err = decodePng(data.Body)
-		if err != nil {
-			return errors.Join(errors.New("can't decode snapshot png"), err)
-		}
+func (b Snapshot) encodeMetadata() map[string]string {
+	if b.Value != nil {
+		x, y := b.GetSize()
+		b.Metadata[keyX] = fmt.Sprint(x)
+		b.Metadata[keyY] = fmt.Sprint(y)
 	}
-	return err
+	b.Metadata[dataHash] = b.Hash.String()
+	return b.Metadata
 }
 
 func (b Snapshot) encode() (*registry.Object, error) {
@@ -67,45 +67,70 @@ func (b Snapshot) encode() (*registry.Object, error) {
 	if b.Value != nil {
 		body, err = encodePng(b.Value)
 		if err != nil {
-			return nil, errors.Join(errors.New("can't encode snapshot png"), err)
+			return nil, errors.Join(ErrEncodeSnapshot, err)
 		}
-		x, y := b.GetSize()
-		b.Metadata[keyX] = fmt.Sprint(x)
-		b.Metadata[keyY] = fmt.Sprint(y)
 	}
-	data := &registry.Object{
+	return &registry.Object{
 		Body: body,
-		Data: b.Metadata,
-	}
-	data.Data[dataHash] = b.Hash.String()
-	return data, nil
+		Data: b.encodeMetadata(),
+	}, nil
 }
 
 func (s *Snapshot) Head(key string) error {
 	data, err := defaultRegistry.Head(key)
 	if err != nil {
-		return errors.Join(errors.New("can't pull snapshot"), err)
+		return errors.Join(ErrPullSnapshot, err)
 	}
 	s.Metadata = data
 	s.Hash = hashString(data[dataHash])
 	return nil
 }
 
+// Pull decodes the png as it's streamed from the registry
 func (s *Snapshot) Pull(key string) error {
-	obj, err := defaultRegistry.Pull(key)
+	body, data, err := registry.PullStream(defaultRegistry, key)
 	if err != nil {
-		return errors.Join(errors.New("can't pull snapshot"), err)
+		return errors.Join(ErrPullSnapshot, err)
 	}
-	return s.decode(*obj)
+	defer body.Close()
+	s.Metadata = data
+	s.Hash = hashString(data[dataHash])
+	s.Value = nil
+	reader := bufio.NewReader(body)
+	if _, err = reader.Peek(1); err == io.EOF {
+		return nil
+	}
+	if s.Value, err = png.Decode(reader); err != nil {
+		return errors.Join(ErrDecodeSnapshot, err)
+	}
+	return nil
 }
 
+// Push streams the png to the registry while it's encoded
 func (s Snapshot) Push(key string) error {
-	obj, err := s.encode()
-	if err != nil {
-		return err
+	if s.Value == nil {
+		obj, _ := s.encode()
+		if err := defaultRegistry.Push(key, *obj); err != nil {
+			return errors.Join(ErrPushSnapshot, err)
+		}
+		return nil
 	}
-	if err = defaultRegistry.Push(key, *obj); err != nil {
-		return errors.Join(errors.New("can't push snapshot"), err)
+	reader, writer := io.Pipe()
+	go func() {
+		err := png.Encode(writer, s.Value)
+		if err != nil {
+			err = errors.Join(ErrEncodeSnapshot, err)
+		}
+		writer.CloseWithError(err)
+	}()
+	err := registry.PushStream(defaultRegistry, key, reader, s.encodeMetadata())
+	// unblock the encoder if the registry stopped reading
+	reader.CloseWithError(err)
+	if err != nil {
+		if errors.Is(err, ErrEncodeSnapshot) {
+			return err
+		}
+		return errors.Join(ErrPushSnapshot, err)
 	}
 	return nil
 }
@@ -114,10 +139,71 @@ type Approval struct {
 	Ts       int64  `json:"ts"`
 	Hash     Hash   `json:"hash"`
 	Approver string `json:"approver"`
+	// Key limits the approval to a baseline key, empty key approves the diff for any baseline
+	Key string `json:"key,omitempty"`
+	// Target optionally limits the approval to a target hash
+	Target *Hash `json:"target,omitempty"`
+	// Comment explains the approval, it's also recorded in the audit log
+	Comment string `json:"comment,omitempty"`
+	// Ticket references an issue the approval was granted for
+	Ticket string `json:"ticket,omitempty"`
+	// Expires is an explicit unix time the approval stops working at, zero means ttl only
+	Expires int64 `json:"expires,omitempty"`
+	// Role of the approver, see Quorum
+	Role string `json:"role,omitempty"`
+	// Signoffs of every approver of the hash, Approver is the latest one
+	Signoffs []Signoff `json:"signoffs,omitempty"`
+}
+
+func (t Approval) String() string {
+	s := fmt.Sprintf("approved by %s at %s", t.Approver, time.Unix(t.Ts, 0).UTC().Format(time.DateTime))
+	if t.Comment != "" {
+		s += ": " + t.Comment
+	}
+	if t.Ticket != "" {
+		s += " (ticket " + t.Ticket + ")"
+	}
+	if t.Expires != 0 {
+		s += ", expires at " + time.Unix(t.Expires, 0).UTC().Format(time.DateTime)
+	}
+	return s
+}
+
+func (t Approval) sameScope(other Approval) bool {
+	if t.Key != other.Key || !t.Hash.Equal(other.Hash, 0) {
+		return false
+	}
+	if t.Target == nil || other.Target == nil {
+		return t.Target == other.Target
+	}
+	return t.Target.Equal(*other.Target, 0)
 }
 
+// Applies reports whether the approval is scoped to the baseline key and target hash
+func (t Approval) Applies(key string, target Hash, distance int) bool {
+	if t.Key != "" && t.Key != key {
+		return false
+	}
+	return t.Target == nil || t.Target.Equal(target, distance)
+}
+
+// DefaultApprovalTTL is the approval lifetime used by Approval.Valid and new matchers
+var DefaultApprovalTTL = time.Hour * 24 * 61
+
 func (t Approval) Valid() bool {
-	return time.Unix(t.Ts, 0).AddDate(0, 2, 0).Compare(time.Now()) == 1
+	return t.ValidFor(DefaultApprovalTTL)
+}
+
+// ValidFor reports whether the approval is younger than ttl and not expired explicitly,
+// non-positive ttl never expires
+func (t Approval) ValidFor(ttl time.Duration) bool {
+	if t.Expires != 0 && t.Expires <= time.Now().Unix() {
+		return false
+	}
+	if ttl <= 0 {
+		return true
+	}
+	return time.Unix(t.Ts, 0).Add(ttl).Compare(time.Now()) == 1
 }
 
 type Approvals struct {
@@ -139,37 +225,97 @@ func (b *Approvals) sort() {
 	return
 }
 
-func (b *Approvals) decline(hash Hash) {
+func (b *Approvals) decline(patch Approval) bool {
 	for n := range b.Value {
-		if b.Value[n].Hash.Equal(hash, 0) {
+		if b.Value[n].sameScope(patch) {
 			b.Value[n] = b.Value[len(b.Value)-1]
 			b.Value = b.Value[:len(b.Value)-1]
-			return
+			return true
 		}
 	}
-	return
+	return false
+}
+
+// declineHash removes approvals of the hash whatever their scope is
+func (b *Approvals) declineHash(hash Hash) (declined []Approval) {
+	var value = []Approval{}
+	for _, approval := range b.Value {
+		if approval.Hash.Equal(hash, 0) {
+			declined = append(declined, approval)
+		} else {
+			value = append(value, approval)
+		}
+	}
+	b.Value = value
+	return declined
+}
+
+func (b Approvals) valid(ttl time.Duration) Approvals {
+	var value = []Approval{}
+	for _, approval := range b.Value {
+		if approval.ValidFor(ttl) {
+			value = append(value, approval)
+		}
+	}
+	return Approvals{Value: value}
+}
+
+func (b *Approvals) prune(ttl time.Duration) (expired []Approval) {
+	var value = []Approval{}
+	for _, approval := range b.Value {
+		if approval.ValidFor(ttl) {
+			value = append(value, approval)
+		} else {
+			expired = append(expired, approval)
+		}
+	}
+	b.Value = value
+	return expired
+}
+
+func (b Approvals) scoped(key string, target Hash, distance int, strict bool) []Approval {
+	var value = []Approval{}
+	for _, approval := range b.Value {
+		if strict && approval.Key == "" {
+			continue
+		}
+		if approval.Applies(key, target, distance) {
+			value = append(value, approval)
+		}
+	}
+	return value
 }
 
 var MaxApprovals = 100
 
-func (b *Approvals) accept(patch Approval) {
+// accept returns approvals evicted to keep no more than max of them
+func (b *Approvals) accept(patch Approval, max int, ttl time.Duration) (evicted []Approval) {
 	patch.Ts = getUnixTs()
 
 	// updating
 	for n, val := range b.Value {
-		if val.Hash.Equal(patch.Hash, 0) {
+		if val.sameScope(patch) {
+			patch.Signoffs = val.signedBy(patch)
 			b.Value[n] = patch
 			return
 		}
 	}
+	patch.Signoffs = []Signoff{{Ts: patch.Ts, Approver: patch.Approver, Role: patch.Role}}
 
 	// overflowed
-	if len(b.Value) >= MaxApprovals {
+	if len(b.Value) >= max {
+		evicted = b.prune(ttl)
+	}
+	if len(b.Value) >= max {
+		// the oldest ones, more than one if max was lowered
 		b.sort()
-		b.Value[0] = patch
+		n := len(b.Value) - max + 1
+		evicted = append(evicted, b.Value[:n]...)
+		b.Value = append([]Approval{patch}, b.Value[n:]...)
 		return
 	}
 
 	// a new one
 	b.Value = append(b.Value, patch)
+	return
 }
@@ -1,6 +1,10 @@
 package gosnap
 
 import (
+	"errors"
+	"fmt"
+	"image"
+	"strings"
 	"testing"
 	"time"
 )
@@ -18,7 +22,7 @@ func TestBaselineApproveUpdate(t *testing.T) {
 	baseline.accept(Approval{
 		Hash:     hashString("1"),
 		Approver: "u3",
-	})
+	}, MaxApprovals, DefaultApprovalTTL)
 	if baseline.Value[0].Ts <= now.Unix() {
 		t.Error("approval ts not updated", baseline.Value[0])
 	}
@@ -47,7 +51,7 @@ func TestBaselineApproveOverflow(t *testing.T) {
 	baseline.accept(Approval{
 		Hash:     hashString("newhash"),
 		Approver: "new_user",
-	})
+	}, MaxApprovals, DefaultApprovalTTL)
 
 	if baseline.Value[0].Ts < now.Unix() {
 		t.Error("approval ts not updated", baseline.Value)
@@ -59,3 +63,120 @@ func TestBaselineApproveOverflow(t *testing.T) {
 		t.Error("approver not updated", baseline.Value)
 	}
 }
+
+func TestApprovalsScoped(t *testing.T) {
+	target := hashString("target")
+	approvals := Approvals{
+		Value: []Approval{
+			{Hash: hashString("1"), Approver: "legacy"},
+			{Hash: hashString("2"), Approver: "page", Key: "page"},
+			{Hash: hashString("3"), Approver: "other", Key: "other"},
+			{Hash: hashString("4"), Approver: "target", Key: "page", Target: &target},
+		},
+	}
+
+	scoped := approvals.scoped("page", target, 0, false)
+	if len(scoped) != 3 {
+		t.Error("expected legacy, page and target approvals", scoped)
+	}
+	scoped = approvals.scoped("page", hashString("changed"), 0, true)
+	if len(scoped) != 1 || scoped[0].Approver != "page" {
+		t.Error("expected only page approval", scoped)
+	}
+}
+
+func TestApprovalsPrune(t *testing.T) {
+	now := time.Now()
+	approvals := &Approvals{
+		Value: []Approval{
+			{Ts: now.Add(-time.Hour * 2).Unix(), Hash: hashString("old")},
+			{Ts: now.Unix(), Hash: hashString("new")},
+		},
+	}
+	if approvals.valid(0).Value[0].Hash.String() != "old" {
+		t.Error("non-positive ttl must never expire", approvals.Value)
+	}
+	if expired := approvals.prune(time.Hour); len(expired) != 1 || approvals.Value[0].Hash.String() != "new" {
+		t.Error("expired approval not pruned", approvals.Value)
+	}
+}
+
+func TestApprovalsAcceptLoweredMax(t *testing.T) {
+	now := time.Now()
+	approvals := &Approvals{}
+	for n := 0; n < 5; n++ {
+		approvals.Value = append(approvals.Value, Approval{Ts: now.Add(time.Duration(n) * time.Second).Unix(), Hash: hashString(fmt.Sprint(n))})
+	}
+	evicted := approvals.accept(Approval{Hash: hashString("new")}, 3, 0)
+	if len(approvals.Value) != 3 || len(evicted) != 3 || evicted[0].Hash.String() != "0" {
+		t.Error("expected trimmed to max", approvals.Value, evicted)
+	}
+	if approvals.Value[0].Hash.String() != "new" || approvals.Value[2].Hash.String() != "4" {
+		t.Error("expected the newest kept", approvals.Value)
+	}
+}
+
+func TestApprovalExpires(t *testing.T) {
+	now := time.Now()
+	approval := Approval{
+		Ts:       now.Unix(),
+		Hash:     hashString("1"),
+		Approver: "u1",
+		Comment:  "new font",
+		Ticket:   "UI-42",
+		Expires:  now.Add(-time.Minute).Unix(),
+	}
+	if approval.ValidFor(0) {
+		t.Error("explicitly expired approval is valid", approval)
+	}
+	if s := approval.String(); !strings.Contains(s, "new font") || !strings.Contains(s, "UI-42") {
+		t.Error("comment and ticket expected", s)
+	}
+}
+
+func TestDeclineScopedByHash(t *testing.T) {
+	SetRegistry(newMemRegistry())
+	synced := NewSyncedOps()
+	target := hashString("t")
+	_ = synced.AcceptApproval("approvals", Approval{Hash: hashString("1"), Approver: "u1", Key: "page", Target: &target})
+	_ = synced.AcceptApproval("approvals", Approval{Hash: hashString("1"), Approver: "u1", Key: "other"})
+	_ = synced.Accept("approvals", hashString("2"), "u1")
+
+	if err := synced.Decline("approvals", hashString("1")); err != nil {
+		t.Fatal(err)
+	}
+	var approvals = new(Approvals)
+	_ = approvals.Pull("approvals")
+	if len(approvals.Value) != 1 || approvals.Value[0].Hash.String() != "2" {
+		t.Error("expected scoped approvals of the hash declined", approvals.Value)
+	}
+	entries, _ := synced.Audit("approvals", AuditFilter{})
+	if len(entries) != 5 || entries[4].Action != AuditDecline {
+		t.Error("expected decline entries", entries)
+	}
+}
+
+func TestSnapshotStream(t *testing.T) {
+	r := newMemRegistry()
+	SetRegistry(r.Streaming())
+	img := testImage(64, 64, 16)
+	if err := (Snapshot{Value: img, Hash: MakeHash(img, 1024), Metadata: map[string]string{}}).Push("page"); err != nil {
+		t.Fatal(err)
+	}
+	var snapshot = new(Snapshot)
+	if err := snapshot.Pull("page"); err != nil || snapshot.Value.Bounds() != img.Bounds() {
+		t.Fatal("unexpected snapshot", snapshot, err)
+	}
+	if r.Calls("pushstream") != 1 || r.Calls("pullstream") != 1 || r.Calls("push") != 0 {
+		t.Error("expected streams", r.Calls("pushstream"), r.Calls("pullstream"))
+	}
+
+	// png can't encode an empty image
+	empty := Snapshot{Value: image.NewNRGBA(image.Rect(0, 0, 0, 0)), Metadata: map[string]string{}}
+	if err := empty.Push("empty"); !errors.Is(err, ErrEncodeSnapshot) || errors.Is(err, ErrPushSnapshot) {
+		t.Error("expected encode error", err)
+	}
+	if _, ok := r.Object("empty"); ok {
+		t.Error("unexpected partial snapshot")
+	}
+}